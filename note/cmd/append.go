@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/rameshg87/tools/note/lib"
+	"github.com/spf13/cobra"
+)
+
+// appendCmd represents the append command
+var appendCmd = &cobra.Command{
+	Use:   "append <name> <text>...",
+	Short: "Append a timestamped entry to a note",
+	Long: `Append the text to the note matching name as an entry headed by the
+current date and time, without opening the editor. The note is created when
+no note matches the name.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			log.Fatal("note: No filename provided")
+		}
+		if len(args) < 2 {
+			log.Fatal("note: No text provided")
+		}
+		file, err := lib.Append(args[0], strings.Join(args[1:], " "))
+		if err != nil {
+			log.Fatal(err)
+		}
+		if out := newRecordWriter(); out != nil {
+			out.printNotes([]string{file})
+		}
+	},
+}
+
+// captureCmd represents the capture command
+var captureCmd = &cobra.Command{
+	Use:   "capture [name]",
+	Short: "Append the standard input to a note",
+	Long: `Append the standard input to the note matching name, the inbox note
+(capture.inbox in the config file) by default, as an entry headed by the
+current date and time:
+
+  make 2>&1 | note capture build-log`,
+	Run: func(cmd *cobra.Command, args []string) {
+		var name string
+		if len(args) > 0 {
+			name = args[0]
+		}
+		text, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			log.Fatal(err)
+		}
+		file, err := lib.Append(name, string(text))
+		if err != nil {
+			log.Fatal(err)
+		}
+		if out := newRecordWriter(); out != nil {
+			out.printNotes([]string{file})
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(appendCmd)
+	RootCmd.AddCommand(captureCmd)
+}
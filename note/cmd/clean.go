@@ -1,24 +1,66 @@
 package cmd
 
 import (
+	"fmt"
 	"log"
+	"os"
 
 	"github.com/rameshg87/tools/note/lib"
 	"github.com/spf13/cobra"
 )
 
+var cleanDryRun bool
+
 // cleanCmd represents the clean command
 var cleanCmd = &cobra.Command{
 	Use:   "clean",
 	Short: "Clean all editor temp files from NOTES_DIR",
+	Long: `Clean all editor temp files from NOTES_DIR.
+
+Files are selected by the clean.rules of the config file, a list of
+rules with a glob or regex matched against base names and optional
+min_age, min_size and max_size conditions. The default rules remove
+vim swap files, emacs auto-save, lock and backup files, .DS_Store,
+desktop.ini and Thumbs.db. Files that may be kept on purpose, like
+.bak files, are only removed once their regex is added to the
+clean.patterns, which extend the rules:
+
+  clean:
+    patterns: ['\.bak$']`,
 	Run: func(cmd *cobra.Command, args []string) {
-		err := lib.Clean()
-		if err != nil {
+		result, err := lib.CleanWith(cleanDryRun)
+		if result == nil {
 			log.Fatal(err)
 		}
+		if out := newRecordWriter(); out != nil {
+			for _, record := range result.Records {
+				out.write(record, noteRow(record))
+			}
+			out.close()
+		} else {
+			printCleanResult(result)
+		}
+		for _, failure := range result.Failures {
+			fmt.Fprintf(os.Stderr, "note: %s\n", failure.Err)
+		}
+		if err != nil {
+			os.Exit(1)
+		}
 	},
 }
 
+func printCleanResult(result *lib.CleanResult) {
+	verb := "Removed"
+	if cleanDryRun {
+		verb = "Would remove"
+	}
+	for _, file := range result.Removed {
+		fmt.Printf("%s %s\n", verb, lib.RelName(file))
+	}
+	fmt.Printf("%s %d files, %d bytes\n", verb, len(result.Removed), result.Size)
+}
+
 func init() {
 	RootCmd.AddCommand(cleanCmd)
+	cleanCmd.Flags().BoolVarP(&cleanDryRun, "dry-run", "n", false, "show what would be removed without removing it")
 }
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/rameshg87/tools/note/lib"
+	"github.com/spf13/cobra"
+)
+
+// conflictsCmd represents the conflicts command
+var conflictsCmd = &cobra.Command{
+	Use:   "conflicts",
+	Short: "List conflict copies created by sync tools",
+	Run: func(cmd *cobra.Command, args []string) {
+		conflicts, err := lib.Conflicts()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if out := newRecordWriter(); out != nil {
+			for _, conflict := range conflicts {
+				out.write(conflict, []string{conflict.Copy, conflict.Original, conflict.Kind, strconv.FormatBool(conflict.Orphan)})
+			}
+			out.close()
+			return
+		}
+		for _, conflict := range conflicts {
+			original := lib.RelName(conflict.Original)
+			if conflict.Orphan {
+				original += " (missing)"
+			}
+			fmt.Printf("%s\t%s\t%s\n", conflict.Kind, lib.RelName(conflict.Copy), original)
+		}
+	},
+}
+
+// conflictsResolveCmd represents the conflicts resolve command
+var conflictsResolveCmd = &cobra.Command{
+	Use:   "resolve <name>",
+	Short: "Merge a conflict copy into its original and trash it",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			log.Fatal("note: No filename provided")
+		}
+		if _, err := lib.ResolveConflict(args[0]); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	conflictsCmd.AddCommand(conflictsResolveCmd)
+	RootCmd.AddCommand(conflictsCmd)
+}
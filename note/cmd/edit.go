@@ -7,7 +7,8 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var create bool
+var editOpts lib.EditOptions
+var editAll bool
 
 // editCmd represents the edit command
 var editCmd = &cobra.Command{
@@ -17,7 +18,13 @@ var editCmd = &cobra.Command{
 		if len(args) < 1 {
 			log.Fatal("note: No filename provided")
 		}
-		err := lib.Edit(args[0], create)
+		editOpts.Prompt = promptVar
+		var err error
+		if editAll {
+			err = lib.EditAll(args[0], editOpts)
+		} else {
+			err = lib.EditWith(args[0], editOpts)
+		}
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -26,5 +33,9 @@ var editCmd = &cobra.Command{
 
 func init() {
 	RootCmd.AddCommand(editCmd)
-	editCmd.Flags().BoolVarP(&create, "create", "c", false, "create")
+	editCmd.Flags().BoolVarP(&editOpts.Create, "create", "c", false, "create")
+	editCmd.Flags().BoolVar(&editOpts.Encrypt, "encrypt", false, "encrypt the note")
+	editCmd.Flags().StringVarP(&editOpts.Template, "template", "t", "", "template to create the note from")
+	editCmd.Flags().StringToStringVar(&editOpts.Vars, "var", nil, "value of a custom template variable (name=value)")
+	editCmd.Flags().BoolVarP(&editAll, "all", "a", false, "look for the note in all notebooks")
 }
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/rameshg87/tools/note/lib"
+	"github.com/spf13/cobra"
+)
+
+var exportOut string
+
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export notes to other formats",
+}
+
+// exportRecord is the outcome of an export for machine-readable output.
+type exportRecord struct {
+	Dir         string   `json:"dir"`
+	Notes       int      `json:"notes"`
+	Attachments int      `json:"attachments"`
+	Skipped     []string `json:"skipped"`
+}
+
+// exportHTMLCmd represents the export html command
+var exportHTMLCmd = &cobra.Command{
+	Use:   "html",
+	Short: "Export notes to a static HTML site",
+	Long: `Export the notes of the selected notebook to a static HTML site in
+the directory given with --out. Every markdown note becomes a page with its
+wiki and markdown links pointing at the other pages, every folder and tag
+gets an index page, other files are copied as attachments and the pages can
+search the notes without a server. A note named index becomes the page of
+its folder. Encrypted notes are never exported.
+
+Existing files in the directory are overwritten but not removed.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if exportOut == "" {
+			log.Fatal("note: No output directory provided")
+		}
+		result, err := lib.ExportHTML(exportOut)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, file := range result.Skipped {
+			log.Printf("note: Skipped encrypted note %s", lib.RelName(file))
+		}
+		if out := newRecordWriter(); out != nil {
+			record := exportRecord{exportOut, result.Notes, result.Attachments, append([]string{}, result.Skipped...)}
+			out.write(record, []string{record.Dir, strconv.Itoa(record.Notes), strconv.Itoa(record.Attachments)})
+			out.close()
+			return
+		}
+		fmt.Printf("Exported %d notes and %d attachments to %s\n", result.Notes, result.Attachments, exportOut)
+	},
+}
+
+func init() {
+	exportCmd.AddCommand(exportHTMLCmd)
+	RootCmd.AddCommand(exportCmd)
+	exportHTMLCmd.Flags().StringVarP(&exportOut, "out", "o", "", "directory to write the site to")
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/rameshg87/tools/note/lib"
+	"github.com/spf13/cobra"
+)
+
+// findCmd represents the find command
+var findCmd = &cobra.Command{
+	Use:   "find <query>",
+	Short: "Find notes matching a query",
+	Long: `Find notes matching a query, e.g.
+
+  note find 'tag:work AND (kafka OR pulsar) NOT draft modified:>2024-01-01 title:"retro"'
+
+Words match the content of notes, ignoring case. Fields match other
+properties of notes:
+
+  path:, name:      part of the path of the note
+  tag:              a tag of the front matter
+  title:            part of the title, or of the name without one
+  created:          the created date of the front matter, or the mtime
+  modified:         the mtime
+  size:             the size, like 10k or 2M
+  content:, text:   the content, like a bare word
+
+Dates and sizes can be compared with >, >=, < and <=. Terms next to
+each other must all match; combine them with AND, OR, NOT and
+parentheses, or negate a term with a leading -. Quote values with
+spaces.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			log.Fatal("note: No query provided")
+		}
+		files, err := lib.Find(strings.Join(args, " "))
+		if err != nil {
+			log.Fatal(err)
+		}
+		if out := newRecordWriter(); out != nil {
+			out.printNotes(files)
+			return
+		}
+		for _, file := range files {
+			fmt.Println(lib.RelName(file))
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(findCmd)
+}
@@ -1,30 +1,174 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"os"
+	"os/signal"
 	"strings"
 
 	"github.com/rameshg87/tools/note/lib"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
+var grepAll bool
+var grepFilesOnly bool
+var grepContext int
+var grepOpts lib.GrepOptions
+
 // grepCmd represents the grep command
 var grepCmd = &cobra.Command{
 	Use:   "grep",
-	Short: "List notes having a particular string",
+	Short: "Search notes for a particular string",
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) < 1 {
 			log.Fatal("note: No filename provided")
 		}
-		files, err := lib.Grep(args[0])
+		opts := grepOpts
+		if !cmd.Flags().Changed("before-context") {
+			opts.Before = grepContext
+		}
+		if !cmd.Flags().Changed("after-context") {
+			opts.After = grepContext
+		}
+		matcher, err := lib.NewMatcher(args[0], opts)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if !cmd.Flags().Changed("jobs") {
+			opts.Concurrency = viper.GetInt("grep.concurrency")
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		p := &matchPrinter{
+			matcher:   matcher,
+			color:     useColor(),
+			filesOnly: grepFilesOnly,
+			separate:  opts.Before > 0 || opts.After > 0,
+			out:       newRecordWriter(),
+		}
+		if grepAll {
+			err = lib.GrepAllContext(ctx, args[0], opts, p.print)
+		} else {
+			err = lib.GrepContext(ctx, args[0], opts, p.print)
+		}
+		p.close()
 		if err != nil {
 			log.Fatal(err)
 		}
-		fmt.Println(strings.Join(lib.BaseNames(files), "\n"))
 	},
 }
 
+func matchName(match lib.Match) string {
+	if match.Notebook != "" {
+		return lib.NotebookFile{Notebook: match.Notebook, File: match.File}.String()
+	}
+	return lib.RelName(match.File)
+}
+
+// matchPrinter prints matches the way grep -n does as they are found. With
+// separate, when context was asked for, groups of lines that are not
+// adjacent are separated with "--". With
+// filesOnly it prints the name of every matching note once instead. With
+// out set, it prints a record of every matching note.
+type matchPrinter struct {
+	matcher   *lib.Matcher
+	color     bool
+	filesOnly bool
+	separate  bool
+	prev      *lib.Match
+	out       *recordWriter
+	record    *lib.NoteRecord
+}
+
+func (p *matchPrinter) print(match lib.Match) error {
+	prev := p.prev
+	p.prev = &match
+	if p.out != nil {
+		if p.record == nil || p.record.Path != match.File {
+			p.writeRecord()
+			record, err := lib.NewNoteRecord(match.File)
+			if err != nil {
+				return err
+			}
+			record.Notebook = match.Notebook
+			p.record = record
+		}
+		if !p.filesOnly {
+			p.record.AddMatch(match)
+		}
+		return nil
+	}
+	if p.filesOnly {
+		if prev == nil || prev.File != match.File {
+			fmt.Println(matchName(match))
+		}
+		return nil
+	}
+	if p.separate && prev != nil && (prev.File != match.File || prev.Line+1 != match.Line) {
+		fmt.Println("--")
+	}
+	sep := ":"
+	if match.Context {
+		sep = "-"
+	}
+	name := matchName(match)
+	line := fmt.Sprint(match.Line)
+	text := match.Text
+	if p.color {
+		name = colorFile + name + colorReset
+		line = colorLineNum + line + colorReset
+		if !match.Context {
+			text = highlight(text, p.matcher)
+		}
+	}
+	fmt.Println(name + sep + line + sep + text)
+	return nil
+}
+
+func (p *matchPrinter) writeRecord() {
+	if p.record == nil {
+		return
+	}
+	if p.filesOnly {
+		p.out.write(p.record, noteRow(p.record))
+	} else {
+		p.out.write(p.record, matchRows(p.record)...)
+	}
+}
+
+// close prints the record of the last matching note.
+func (p *matchPrinter) close() {
+	if p.out != nil {
+		p.writeRecord()
+		p.out.close()
+	}
+}
+
+func highlight(text string, matcher *lib.Matcher) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range matcher.FindAll(text) {
+		b.WriteString(text[last:loc[0]])
+		b.WriteString(colorMatch + text[loc[0]:loc[1]] + colorReset)
+		last = loc[1]
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
 func init() {
 	RootCmd.AddCommand(grepCmd)
+	flags := grepCmd.Flags()
+	flags.BoolVarP(&grepAll, "all", "a", false, "search all notebooks")
+	flags.BoolVarP(&grepFilesOnly, "files-with-matches", "l", false, "print only the names of matching notes")
+	flags.BoolVarP(&grepOpts.Regexp, "extended-regexp", "E", false, "treat the pattern as a regular expression")
+	flags.BoolVarP(&grepOpts.IgnoreCase, "ignore-case", "i", false, "ignore case distinctions")
+	flags.IntVarP(&grepContext, "context", "C", 0, "print NUM lines of context around matches")
+	flags.IntVarP(&grepOpts.Before, "before-context", "B", 0, "print NUM lines of context before matches")
+	flags.IntVarP(&grepOpts.After, "after-context", "A", 0, "print NUM lines of context after matches")
+	flags.IntVarP(&grepOpts.MaxCount, "max-count", "m", 0, "stop after NUM matching lines")
+	flags.IntVarP(&grepOpts.Concurrency, "jobs", "j", 0, "number of notes searched in parallel (default is the number of CPUs)")
 }
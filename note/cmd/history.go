@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/rameshg87/tools/note/lib"
+	"github.com/spf13/cobra"
+)
+
+// logCmd represents the log command
+var logCmd = &cobra.Command{
+	Use:   "log <name>",
+	Short: "Show the committed revisions of a note",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			log.Fatal("note: No filename provided")
+		}
+		revisions, err := lib.Log(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		if out := newRecordWriter(); out != nil {
+			for _, rev := range revisions {
+				out.write(rev, []string{rev.Hash, formatTime(rev.Date), rev.Subject})
+			}
+			out.close()
+			return
+		}
+		for _, rev := range revisions {
+			fmt.Printf("%s %s %s\n", rev.Hash[:7], rev.Date.Format("2006-01-02 15:04"), rev.Subject)
+		}
+	},
+}
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff <name> [rev]",
+	Short: "Show changes to a note since the last or the given revision",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			log.Fatal("note: No filename provided")
+		}
+		var rev string
+		if len(args) > 1 {
+			rev = args[1]
+		}
+		diff, err := lib.Diff(args[0], rev, useColor())
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Print(diff)
+	},
+}
+
+// restoreCmd represents the restore command
+var restoreCmd = &cobra.Command{
+	Use:   "restore <name> <rev>",
+	Short: "Restore a note to a previous revision",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 2 {
+			log.Fatal("note: Filename and revision required")
+		}
+		if err := lib.Restore(args[0], args[1]); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(logCmd)
+	RootCmd.AddCommand(diffCmd)
+	RootCmd.AddCommand(restoreCmd)
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/rameshg87/tools/note/lib"
+	"github.com/spf13/cobra"
+)
+
+// indexCmd represents the index command
+var indexCmd = &cobra.Command{
+	Use:   "index",
+	Short: "Manage the search index used by grep",
+	Long: `Manage the search index used by grep.
+
+Once built, the index is kept up to date by grep itself, which
+re-reads only the notes whose size or modification time changed.
+While note watch runs, it keeps the index up to date instead. Without
+an index grep scans every note.`,
+}
+
+// indexRecord is the outcome of an index rebuild for machine-readable
+// output.
+type indexRecord struct {
+	Notes int `json:"notes"`
+}
+
+// indexRebuildCmd represents the index rebuild command
+var indexRebuildCmd = &cobra.Command{
+	Use:   "rebuild",
+	Short: "Build the search index from scratch",
+	Run: func(cmd *cobra.Command, args []string) {
+		count, err := lib.RebuildIndex()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if out := newRecordWriter(); out != nil {
+			out.write(indexRecord{count}, []string{strconv.Itoa(count)})
+			out.close()
+			return
+		}
+		fmt.Printf("Indexed %d notes\n", count)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(indexCmd)
+	indexCmd.AddCommand(indexRebuildCmd)
+}
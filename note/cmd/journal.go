@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/rameshg87/tools/note/lib"
+	"github.com/spf13/cobra"
+)
+
+var journalOpts lib.EditOptions
+var journalPeriod string
+
+func openJournal(when string) {
+	date, err := lib.ParseDate(when, time.Now())
+	if err != nil {
+		log.Fatal(err)
+	}
+	journalOpts.Prompt = promptVar
+	if err := lib.Journal(date, journalOpts); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// journalCmd represents the journal command
+var journalCmd = &cobra.Command{
+	Use:   "journal [date]",
+	Short: "Open the journal entry of a day",
+	Long: `Open the journal entry of a day, creating it when needed.
+
+The date defaults to today and can be given as "yesterday",
+"last friday", "next monday", "3 days ago", "2017-03-04" and so on.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		openJournal(strings.Join(args, " "))
+	},
+}
+
+// todayCmd represents the today command
+var todayCmd = &cobra.Command{
+	Use:   "today",
+	Short: "Open today's journal entry",
+	Run: func(cmd *cobra.Command, args []string) {
+		openJournal("today")
+	},
+}
+
+// journalLsCmd represents the journal ls command
+var journalLsCmd = &cobra.Command{
+	Use:   "ls [date]",
+	Short: "List the journal entries of the period around a day",
+	Run: func(cmd *cobra.Command, args []string) {
+		date, err := lib.ParseDate(strings.Join(args, " "), time.Now())
+		if err != nil {
+			log.Fatal(err)
+		}
+		var from, to time.Time
+		switch journalPeriod {
+		case "week":
+			from = date.AddDate(0, 0, -(int(date.Weekday())+6)%7)
+			to = from.AddDate(0, 0, 6)
+		case "month":
+			from = date.AddDate(0, 0, 1-date.Day())
+			to = from.AddDate(0, 1, -1)
+		case "year":
+			from = date.AddDate(0, 0, 1-date.YearDay())
+			to = from.AddDate(1, 0, -1)
+		case "all":
+		default:
+			log.Fatalf("note: Unknown period '%s'", journalPeriod)
+		}
+		entries, err := lib.JournalEntries(from, to)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if out := newRecordWriter(); out != nil {
+			var files []string
+			for _, entry := range entries {
+				files = append(files, entry.File)
+			}
+			out.printNotes(files)
+			return
+		}
+		for _, entry := range entries {
+			fmt.Printf("%s\t%s\n", entry.Date.Format("Mon 2006-01-02"), lib.JournalName(entry.Date))
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(journalCmd)
+	RootCmd.AddCommand(todayCmd)
+	journalCmd.AddCommand(journalLsCmd)
+	for _, cmd := range []*cobra.Command{journalCmd, todayCmd} {
+		cmd.Flags().StringVarP(&journalOpts.Template, "template", "t", "", "template to create the entry from")
+		cmd.Flags().StringToStringVar(&journalOpts.Vars, "var", nil, "value of a custom template variable (name=value)")
+	}
+	flags := journalLsCmd.Flags()
+	flags.StringVar(&journalPeriod, "period", "week", "period to list: week, month, year or all")
+	flags.Bool("week", false, "list the entries of the week (same as --period=week)")
+	flags.Bool("month", false, "list the entries of the month (same as --period=month)")
+	flags.Bool("year", false, "list the entries of the year (same as --period=year)")
+	journalLsCmd.PreRun = func(cmd *cobra.Command, args []string) {
+		for _, period := range []string{"week", "month", "year"} {
+			if set, _ := cmd.Flags().GetBool(period); set {
+				journalPeriod = period
+			}
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/rameshg87/tools/note/lib"
+	"github.com/spf13/cobra"
+)
+
+// keygenCmd represents the keygen command
+var keygenCmd = &cobra.Command{
+	Use:   "keygen",
+	Short: "Generate the identity used to encrypt notes",
+	Run: func(cmd *cobra.Command, args []string) {
+		recipient, err := lib.GenerateIdentity()
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Wrote %s\nPublic key: %s\n", lib.GetConfig().IdentityFile, recipient)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(keygenCmd)
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/rameshg87/tools/note/lib"
+	"github.com/spf13/cobra"
+)
+
+// linkRecord is a link of a note for machine-readable output. Path and
+// Name are empty when the link doesn't resolve to a note.
+type linkRecord struct {
+	Kind   string `json:"kind"`
+	Target string `json:"target"`
+	Line   int    `json:"line"`
+	Path   string `json:"path"`
+	Name   string `json:"name"`
+}
+
+// linksCmd represents the links command
+var linksCmd = &cobra.Command{
+	Use:   "links <name>",
+	Short: "List the links of a note to other notes",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			log.Fatal("note: No filename provided")
+		}
+		links, err := lib.Links(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		if out := newRecordWriter(); out != nil {
+			for _, link := range links {
+				record := linkRecord{link.Kind, link.Target, link.Line, link.To, ""}
+				if link.To != "" {
+					record.Name = lib.RelName(link.To)
+				}
+				out.write(record, []string{record.Target, strconv.Itoa(record.Line), record.Path, record.Name})
+			}
+			out.close()
+			return
+		}
+		for _, link := range links {
+			to := "(unresolved)"
+			if link.To != "" {
+				to = lib.RelName(link.To)
+			}
+			fmt.Printf("%d:%s\t%s\n", link.Line, link.Target, to)
+		}
+	},
+}
+
+// backlinksCmd represents the backlinks command
+var backlinksCmd = &cobra.Command{
+	Use:   "backlinks <name>",
+	Short: "List the notes linking to a note",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			log.Fatal("note: No filename provided")
+		}
+		links, err := lib.Backlinks(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		if out := newRecordWriter(); out != nil {
+			var record *lib.NoteRecord
+			for _, link := range links {
+				if record == nil || record.Path != link.File {
+					if record != nil {
+						out.write(record, matchRows(record)...)
+					}
+					if record, err = lib.NewNoteRecord(link.File); err != nil {
+						log.Fatal(err)
+					}
+				}
+				record.AddMatch(lib.Match{Line: link.Line, Column: link.Start + 1, Text: link.Text})
+			}
+			if record != nil {
+				out.write(record, matchRows(record)...)
+			}
+			out.close()
+			return
+		}
+		for _, link := range links {
+			fmt.Printf("%s:%d:%s\n", lib.RelName(link.File), link.Line, link.Text)
+		}
+	},
+}
+
+// orphansCmd represents the orphans command
+var orphansCmd = &cobra.Command{
+	Use:   "orphans",
+	Short: "List notes without links from or to other notes",
+	Run: func(cmd *cobra.Command, args []string) {
+		files, err := lib.Orphans()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if out := newRecordWriter(); out != nil {
+			out.printNotes(files)
+			return
+		}
+		for _, file := range files {
+			fmt.Println(lib.RelName(file))
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(linksCmd)
+	RootCmd.AddCommand(backlinksCmd)
+	RootCmd.AddCommand(orphansCmd)
+}
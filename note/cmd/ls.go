@@ -7,8 +7,12 @@ import (
 
 	"github.com/rameshg87/tools/note/lib"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
+var lsAll bool
+var lsFilter lib.Filter
+
 // lsCmd represents the ls command
 var lsCmd = &cobra.Command{
 	Use:   "ls",
@@ -18,14 +22,41 @@ var lsCmd = &cobra.Command{
 		if len(args) > 0 {
 			filename = args[0]
 		}
-		files, err := lib.List(filename)
+		out := newRecordWriter()
+		if lsAll {
+			files, err := lib.ListAll(filename, lsFilter)
+			if err != nil {
+				log.Fatal(err)
+			}
+			for _, file := range files {
+				if out != nil {
+					out.writeNote(file.File, file.Notebook)
+				} else {
+					fmt.Println(file)
+				}
+			}
+			if out != nil {
+				out.close()
+			}
+			return
+		}
+		files, err := lib.ListWith(filename, lsFilter)
 		if err != nil {
 			log.Fatal(err)
 		}
-		fmt.Println(strings.Join(lib.BaseNames(files), "\n"))
+		if out != nil {
+			out.printNotes(files)
+			return
+		}
+		fmt.Println(strings.Join(lib.RelNames(files), "\n"))
 	},
 }
 
 func init() {
 	RootCmd.AddCommand(lsCmd)
+	lsCmd.Flags().BoolVarP(&lsAll, "all", "a", false, "list notes of all notebooks")
+	lsCmd.Flags().StringArrayVarP(&lsFilter.Tags, "tag", "t", nil, "list only notes having this tag (repeatable)")
+	lsCmd.Flags().BoolVar(&lsFilter.AnyTag, "any", false, "list notes having any of the tags instead of all of them")
+	lsCmd.Flags().String("sort", "", "order of the notes: recent, frecency, atime, mtime, name, size or created")
+	viper.BindPFlag("sort", lsCmd.Flags().Lookup("sort"))
 }
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"log"
+
+	"github.com/rameshg87/tools/note/lib"
+	"github.com/spf13/cobra"
+)
+
+// mkdirRecord is a folder created by mkdir for machine-readable output.
+type mkdirRecord struct {
+	Path string `json:"path"`
+	Name string `json:"name"`
+}
+
+// mkdirCmd represents the mkdir command
+var mkdirCmd = &cobra.Command{
+	Use:   "mkdir <folder>",
+	Short: "Create a folder for notes",
+	Long: `Create a folder, and any missing parents, in the notes directory.
+Notes in folders are named by their path, as in "note edit -c work/plan.md",
+which also creates missing folders.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			log.Fatal("note: No folder provided")
+		}
+		out := newRecordWriter()
+		for _, name := range args {
+			dir, err := lib.Mkdir(name)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if out != nil {
+				record := mkdirRecord{dir, lib.RelName(dir)}
+				out.write(record, []string{record.Path, record.Name})
+			}
+		}
+		if out != nil {
+			out.close()
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(mkdirCmd)
+}
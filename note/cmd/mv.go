@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/rameshg87/tools/note/lib"
+	"github.com/spf13/cobra"
+)
+
+var mvDryRun bool
+
+// moveRecord is a move for machine-readable output.
+type moveRecord struct {
+	From     string          `json:"from"`
+	To       string          `json:"to"`
+	DryRun   bool            `json:"dry_run"`
+	Rewrites []rewriteRecord `json:"rewrites"`
+}
+
+// rewriteRecord is a line rewritten by a move.
+type rewriteRecord struct {
+	Path string `json:"path"`
+	Name string `json:"name"`
+	Line int    `json:"line"`
+	Old  string `json:"old"`
+	New  string `json:"new"`
+}
+
+// mvCmd represents the mv command
+var mvCmd = &cobra.Command{
+	Use:   "mv <old> <new>",
+	Short: "Rename or move a note, updating links to it",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 2 {
+			log.Fatal("note: Old and new names required")
+		}
+		result, err := lib.Move(args[0], args[1], mvDryRun)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if out := newRecordWriter(); out != nil {
+			writeMove(out, result)
+			out.close()
+			return
+		}
+		if !mvDryRun {
+			return
+		}
+		fmt.Printf("rename %s => %s\n", lib.RelName(result.From), lib.RelName(result.To))
+		printRewrites(result.Rewrites)
+	},
+}
+
+// writeMove prints the record of a move, with a tsv row for every
+// rewritten line, or a single one when there are none.
+func writeMove(out *recordWriter, result *lib.MoveResult) {
+	record := moveRecord{From: result.From, To: result.To, DryRun: mvDryRun, Rewrites: []rewriteRecord{}}
+	rows := [][]string{}
+	for _, rewrite := range result.Rewrites {
+		r := rewriteRecord{rewrite.File, lib.RelName(rewrite.File), rewrite.Line, rewrite.Old, rewrite.New}
+		record.Rewrites = append(record.Rewrites, r)
+		rows = append(rows, []string{record.To, record.From, r.Path, strconv.Itoa(r.Line), r.Old, r.New})
+	}
+	if len(rows) == 0 {
+		rows = append(rows, []string{record.To, record.From, "", "", "", ""})
+	}
+	out.write(record, rows...)
+}
+
+// printRewrites prints rewritten lines as a unified diff.
+func printRewrites(rewrites []lib.Rewrite) {
+	color := useColor()
+	for i, rewrite := range rewrites {
+		if i == 0 || rewrites[i-1].File != rewrite.File {
+			name := lib.RelName(rewrite.File)
+			fmt.Printf("--- a/%s\n+++ b/%s\n", name, name)
+		}
+		old, new := "-"+rewrite.Old, "+"+rewrite.New
+		if color {
+			old, new = colorMatch+old+colorReset, colorLineNum+new+colorReset
+		}
+		fmt.Printf("@@ -%d +%d @@\n%s\n%s\n", rewrite.Line, rewrite.Line, old, new)
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(mvCmd)
+	mvCmd.Flags().BoolVarP(&mvDryRun, "dry-run", "n", false, "show the changes without making them")
+}
@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/rameshg87/tools/note/lib"
+	"github.com/spf13/cobra"
+)
+
+// notebookCmd represents the notebook command
+var notebookCmd = &cobra.Command{
+	Use:   "notebook",
+	Short: "Manage named notebooks",
+}
+
+// notebookName returns the name a notebook is stored under. The config
+// file's keys are case-insensitive, so names are kept in lower case.
+func notebookName(name string) string {
+	return strings.ToLower(name)
+}
+
+// notebookAddCmd represents the notebook add command
+var notebookAddCmd = &cobra.Command{
+	Use:   "add <name> <dir>",
+	Short: "Add a notebook rooted at the given directory",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 2 {
+			log.Fatal("note: Notebook name and directory required")
+		}
+		dir, err := filepath.Abs(expandHome(args[1]))
+		if err != nil {
+			log.Fatal(err)
+		}
+		if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+			log.Fatalf("note: %s is not a directory", dir)
+		}
+		err = updateConfigFile(func(settings map[string]interface{}) error {
+			notebooks, _ := settings["notebooks"].(map[string]interface{})
+			if notebooks == nil {
+				notebooks = make(map[string]interface{})
+			}
+			notebooks[notebookName(args[0])] = dir
+			settings["notebooks"] = notebooks
+			return nil
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// notebookRecord is a notebook for machine-readable output.
+type notebookRecord struct {
+	Name    string `json:"name"`
+	Dir     string `json:"dir"`
+	Current bool   `json:"current"`
+}
+
+// notebookLsCmd represents the notebook ls command
+var notebookLsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List notebooks",
+	Run: func(cmd *cobra.Command, args []string) {
+		conf := lib.GetConfig()
+		current, _ := conf.Root()
+		out := newRecordWriter()
+		for _, name := range lib.NotebookNames() {
+			if out != nil {
+				record := notebookRecord{name, conf.Notebooks[name], conf.Notebooks[name] == current}
+				out.write(record, []string{record.Dir, record.Name, strconv.FormatBool(record.Current)})
+				continue
+			}
+			marker := " "
+			if conf.Notebooks[name] == current {
+				marker = "*"
+			}
+			fmt.Printf("%s %s\t%s\n", marker, name, conf.Notebooks[name])
+		}
+		if out != nil {
+			out.close()
+		}
+	},
+}
+
+// notebookRmCmd represents the notebook rm command
+var notebookRmCmd = &cobra.Command{
+	Use:   "rm <name>",
+	Short: "Remove a notebook, leaving its notes in place",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			log.Fatal("note: No notebook provided")
+		}
+		name := notebookName(args[0])
+		err := updateConfigFile(func(settings map[string]interface{}) error {
+			notebooks, _ := settings["notebooks"].(map[string]interface{})
+			if _, ok := notebooks[name]; !ok {
+				return lib.NotebookNotFoundError(name)
+			}
+			delete(notebooks, name)
+			if settings["default_notebook"] == name {
+				delete(settings, "default_notebook")
+			}
+			return nil
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// notebookDefaultCmd represents the notebook default command
+var notebookDefaultCmd = &cobra.Command{
+	Use:   "default <name>",
+	Short: "Set the notebook used when --notebook is not given",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			log.Fatal("note: No notebook provided")
+		}
+		name := notebookName(args[0])
+		if _, ok := lib.GetConfig().Notebooks[name]; !ok {
+			log.Fatal(lib.NotebookNotFoundError(name))
+		}
+		err := updateConfigFile(func(settings map[string]interface{}) error {
+			settings["default_notebook"] = name
+			return nil
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(notebookCmd)
+	notebookCmd.AddCommand(notebookAddCmd)
+	notebookCmd.AddCommand(notebookLsCmd)
+	notebookCmd.AddCommand(notebookRmCmd)
+	notebookCmd.AddCommand(notebookDefaultCmd)
+}
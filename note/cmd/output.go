@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rameshg87/tools/note/lib"
+	"github.com/spf13/viper"
+)
+
+// Formats of --output. Text is meant for people and printed by every
+// command its own way; the others print records meant for scripts.
+const (
+	outputText  = "text"
+	outputJSON  = "json"
+	outputJSONL = "jsonl"
+	outputTSV   = "tsv"
+	outputNull  = "null"
+)
+
+func validOutput(format string) bool {
+	switch format {
+	case outputText, outputJSON, outputJSONL, outputTSV, outputNull:
+		return true
+	}
+	return false
+}
+
+// recordWriter prints the records of a command in the format chosen with
+// --output. json prints an array of all records once closed, jsonl a record
+// per line, tsv a line per row of a record and null the first field of
+// every record followed by a NUL byte, for xargs -0.
+type recordWriter struct {
+	format  string
+	w       *bufio.Writer
+	records []interface{}
+}
+
+// newRecordWriter returns nil when the output is text.
+func newRecordWriter() *recordWriter {
+	format := viper.GetString("output")
+	if format == "" || format == outputText {
+		return nil
+	}
+	return &recordWriter{format: format, w: bufio.NewWriter(os.Stdout), records: []interface{}{}}
+}
+
+var tsvEscaper = strings.NewReplacer("\\", "\\\\", "\t", "\\t", "\n", "\\n", "\r", "\\r")
+
+// write prints record, with rows holding its tsv fields.
+func (o *recordWriter) write(record interface{}, rows ...[]string) {
+	switch o.format {
+	case outputJSON:
+		o.records = append(o.records, record)
+	case outputJSONL:
+		data, err := json.Marshal(record)
+		if err != nil {
+			log.Fatal(err)
+		}
+		o.w.Write(append(data, '\n'))
+	case outputTSV:
+		for _, row := range rows {
+			for i, field := range row {
+				row[i] = tsvEscaper.Replace(field)
+			}
+			fmt.Fprintln(o.w, strings.Join(row, "\t"))
+		}
+	case outputNull:
+		if len(rows) > 0 && len(rows[0]) > 0 {
+			o.w.WriteString(rows[0][0] + "\x00")
+		}
+	}
+}
+
+// close prints what is left to print, exiting on errors.
+func (o *recordWriter) close() {
+	if o.format == outputJSON {
+		encoder := json.NewEncoder(o.w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(o.records); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := o.w.Flush(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// requireText exits when --output asks for records, for the commands whose
+// output is only meant for people.
+func requireText(command string) {
+	if out := newRecordWriter(); out != nil {
+		log.Fatalf("note: %s has no --output %s, only text", command, out.format)
+	}
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// noteRow returns the tsv fields of a note.
+func noteRow(record *lib.NoteRecord) []string {
+	return []string{
+		record.Path,
+		record.Name,
+		strconv.FormatInt(record.Size, 10),
+		formatTime(record.Mtime),
+		formatTime(record.Atime),
+		strings.Join(record.Tags, ","),
+		record.Title,
+	}
+}
+
+// matchRows returns the tsv fields of the match lines of a note.
+func matchRows(record *lib.NoteRecord) [][]string {
+	var rows [][]string
+	for _, match := range record.Matches {
+		rows = append(rows, []string{
+			record.Path,
+			record.Name,
+			strconv.Itoa(match.Line),
+			strconv.Itoa(match.Column),
+			match.Text,
+		})
+	}
+	return rows
+}
+
+// writeNote prints the record of a note, logging notes that vanished.
+func (o *recordWriter) writeNote(file, notebook string) {
+	record, err := lib.NewNoteRecord(file)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	record.Notebook = notebook
+	o.write(record, noteRow(record))
+}
+
+// printNotes prints the records of notes and closes o.
+func (o *recordWriter) printNotes(files []string) {
+	for _, file := range files {
+		o.writeNote(file, "")
+	}
+	o.close()
+}
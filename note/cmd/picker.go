@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rameshg87/tools/note/lib"
+	"golang.org/x/term"
+)
+
+var errNotChosen = errors.New("note: No note chosen")
+
+// pickerRows is the most notes the picker shows at once.
+const pickerRows = 10
+
+// pickerName returns how file is shown in the picker: relative to the root
+// of the selected notebook, or prefixed with its notebook when it is in
+// another one.
+func pickerName(file string) string {
+	conf := lib.GetConfig()
+	root, _ := conf.Root()
+	notebook, ok := lib.NotebookOf(file)
+	if !ok || conf.Notebooks[notebook] == root {
+		return lib.RelName(file)
+	}
+	return lib.NotebookFile{Notebook: notebook, File: file}.String()
+}
+
+type picker struct {
+	names    []string
+	query    string
+	matches  []int
+	selected int
+	width    int
+}
+
+// filter updates the notes matching the query, best match first.
+func (p *picker) filter() {
+	scores := make(map[int]int)
+	p.matches = p.matches[:0]
+	for i, name := range p.names {
+		if score, ok := lib.FuzzyScore(p.query, name); ok {
+			scores[i] = score
+			p.matches = append(p.matches, i)
+		}
+	}
+	sort.SliceStable(p.matches, func(i, j int) bool {
+		return scores[p.matches[i]] > scores[p.matches[j]]
+	})
+	p.selected = 0
+}
+
+// render draws the prompt followed by the matching notes and leaves the
+// cursor at the end of the prompt.
+func (p *picker) render() {
+	var b strings.Builder
+	b.WriteString("\r\x1b[J")
+	prompt := fmt.Sprintf("%d/%d > %s", len(p.matches), len(p.names), p.query)
+	b.WriteString(prompt)
+	rows := 0
+	for i, idx := range p.matches {
+		if i == pickerRows {
+			break
+		}
+		name := []rune(p.names[idx])
+		if p.width > 4 && len(name) > p.width-3 {
+			name = name[:p.width-3]
+		}
+		b.WriteString("\r\n")
+		if i == p.selected {
+			b.WriteString("> \x1b[7m" + string(name) + colorReset)
+		} else {
+			b.WriteString("  " + string(name))
+		}
+		rows++
+	}
+	if rows > 0 {
+		fmt.Fprintf(&b, "\x1b[%dA", rows)
+	}
+	fmt.Fprintf(&b, "\r\x1b[%dC", utf8.RuneCountInString(prompt))
+	os.Stderr.WriteString(b.String())
+}
+
+func (p *picker) move(delta int) {
+	limit := len(p.matches)
+	if limit > pickerRows {
+		limit = pickerRows
+	}
+	if limit == 0 {
+		return
+	}
+	p.selected = (p.selected + delta + limit) % limit
+}
+
+// pickNote lets the user choose one of files on the terminal. The arrow
+// keys move the selection, typing filters the notes and enter chooses one.
+func pickNote(files []string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return "", err
+	}
+	defer term.Restore(fd, state)
+	defer os.Stderr.WriteString("\r\x1b[J")
+
+	// List puts the most relevant note last, the picker shows it first.
+	p := &picker{}
+	for i := len(files) - 1; i >= 0; i-- {
+		p.names = append(p.names, pickerName(files[i]))
+	}
+	if width, _, err := term.GetSize(int(os.Stderr.Fd())); err == nil {
+		p.width = width
+	}
+	p.filter()
+	buf := make([]byte, 64)
+	for {
+		p.render()
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return "", err
+		}
+		key := string(buf[:n])
+		switch key {
+		case "\x1b[A", "\x1bOA", "\x10":
+			p.move(-1)
+		case "\x1b[B", "\x1bOB", "\x0e", "\t":
+			p.move(1)
+		case "\r", "\n":
+			if len(p.matches) == 0 {
+				continue
+			}
+			return files[len(files)-1-p.matches[p.selected]], nil
+		case "\x1b", "\x03", "\x04":
+			return "", errNotChosen
+		case "\x7f", "\x08":
+			if p.query != "" {
+				_, size := utf8.DecodeLastRuneInString(p.query)
+				p.query = p.query[:len(p.query)-size]
+				p.filter()
+			}
+		case "\x15":
+			p.query = ""
+			p.filter()
+		default:
+			if key[0] >= 0x20 && key[0] != 0x7f && utf8.ValidString(key) {
+				p.query += key
+				p.filter()
+			}
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/rameshg87/tools/note/lib"
+	"github.com/spf13/cobra"
+)
+
+var recentCount int
+
+// recentCmd represents the recent command
+var recentCmd = &cobra.Command{
+	Use:   "recent",
+	Short: "List recently opened notes",
+	Long: `List the notes most recently opened with note, the most recent
+one last.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		notes, err := lib.Recent()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if recentCount > 0 && len(notes) > recentCount {
+			notes = notes[len(notes)-recentCount:]
+		}
+		if out := newRecordWriter(); out != nil {
+			var files []string
+			for _, note := range notes {
+				files = append(files, note.File)
+			}
+			out.printNotes(files)
+			return
+		}
+		for _, note := range notes {
+			fmt.Println(lib.RelName(note.File))
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(recentCmd)
+	recentCmd.Flags().IntVarP(&recentCount, "count", "n", 10, "number of notes to list, 0 for all")
+}
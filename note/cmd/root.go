@@ -2,13 +2,22 @@ package cmd
 
 import (
 	"fmt"
+	"io/ioutil"
+	"log"
 	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/rameshg87/tools/note/lib"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
 )
 
 var cfgFile string
 
+const defaultNotebook = "default"
+
 // RootCmd represents the base command when called without any subcommands
 var RootCmd = &cobra.Command{
 	Use:   "note",
@@ -29,8 +38,163 @@ func Execute() {
 
 func init() {
 	cobra.OnInitialize(initConfig)
+	flags := RootCmd.PersistentFlags()
+	flags.StringVar(&cfgFile, "config", "", "config file (default is "+filepath.Join(configDir(), "config.yaml")+")")
+	flags.String("notes-dir", "", "directory holding the notes")
+	flags.String("editor", "", "editor command used to edit notes")
+	flags.String("notebook", "", "name of the notebook to use")
+	flags.Bool("no-interactive", false, "fail on ambiguous note names instead of asking")
+	flags.String("output", outputText, "output format: text, json, jsonl, tsv or null")
+	viper.BindPFlag("notes_dir", flags.Lookup("notes-dir"))
+	viper.BindPFlag("editor", flags.Lookup("editor"))
+	viper.BindPFlag("notebook", flags.Lookup("notebook"))
+	viper.BindPFlag("no_interactive", flags.Lookup("no-interactive"))
+	viper.BindPFlag("output", flags.Lookup("output"))
+}
+
+// configDir returns the directory holding the note config file.
+func configDir() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "note")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "note")
 }
 
 // initConfig reads in config file and ENV variables if set.
 func initConfig() {
+	if cfgFile != "" {
+		viper.SetConfigFile(cfgFile)
+	} else {
+		viper.AddConfigPath(configDir())
+		viper.SetConfigName("config")
+	}
+	viper.SetDefault("sort", lib.SortRecent)
+	viper.SetDefault("git.autocommit", true)
+	viper.SetDefault("journal.layout", lib.DefaultJournalLayout)
+	viper.SetDefault("journal.template", "journal")
+	viper.SetDefault("capture.inbox", lib.DefaultInbox)
+	viper.SetDefault("show.style", "dark")
+	viper.SetDefault("watch.use_daemon", true)
+	viper.SetDefault("encryption.identity_file", filepath.Join(configDir(), "identity.txt"))
+	viper.SetDefault("encryption.passphrase_file", filepath.Join(configDir(), "passphrase"))
+
+	// NOTES_DIR and EDITOR keep working as before, every other key can
+	// be overridden with a NOTE_ prefixed variable, e.g. NOTE_SORT.
+	viper.SetEnvPrefix("note")
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	viper.AutomaticEnv()
+	viper.BindEnv("notes_dir", "NOTES_DIR")
+	viper.BindEnv("editor", "EDITOR")
+
+	if err := viper.ReadInConfig(); err != nil {
+		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
+			log.Fatal(err)
+		}
+	}
+
+	if output := viper.GetString("output"); !validOutput(output) {
+		log.Fatal("note: Unknown output format '" + output + "'")
+	}
+
+	// notes_dir is available as the "default" notebook next to the ones
+	// added with "note notebook add".
+	notesDir := expandHome(viper.GetString("notes_dir"))
+	notebooks := make(map[string]string)
+	for name, dir := range viper.GetStringMapString("notebooks") {
+		notebooks[name] = expandHome(dir)
+	}
+	if _, ok := notebooks[defaultNotebook]; !ok && notesDir != "" {
+		notebooks[defaultNotebook] = notesDir
+	}
+	notebook := viper.GetString("notebook")
+	if notebook == "" {
+		notebook = viper.GetString("default_notebook")
+	}
+	notebook = notebookName(notebook)
+	cleanRules := lib.DefaultCleanRules
+	if viper.IsSet("clean.rules") {
+		cleanRules = nil
+		if err := viper.UnmarshalKey("clean.rules", &cleanRules); err != nil {
+			log.Fatal(err)
+		}
+	}
+	lib.SetConfig(&lib.Config{
+		NotesDir:         notesDir,
+		Notebook:         notebook,
+		Notebooks:        notebooks,
+		Editor:           viper.GetString("editor"),
+		Ignore:           viper.GetStringSlice("ignore"),
+		Sort:             viper.GetString("sort"),
+		CleanRules:       cleanRules,
+		CleanPatterns:    viper.GetStringSlice("clean.patterns"),
+		AutoCommit:       viper.GetBool("git.autocommit"),
+		IdentityFile:     expandHome(viper.GetString("encryption.identity_file")),
+		PassphraseFile:   expandHome(viper.GetString("encryption.passphrase_file")),
+		TemplatesDir:     expandHome(viper.GetString("templates.dir")),
+		DefaultTemplates: viper.GetStringMapString("templates.defaults"),
+		JournalLayout:    viper.GetString("journal.layout"),
+		JournalTemplate:  viper.GetString("journal.template"),
+		Inbox:            viper.GetString("capture.inbox"),
+		UseDaemon:        viper.GetBool("watch.use_daemon"),
+		Choose:           chooser(),
+	})
+}
+
+// chooser returns the picker used for ambiguous note names, or nil when
+// not running interactively.
+func chooser() func([]string) (string, error) {
+	if viper.GetBool("no_interactive") || !isTerminal(os.Stdin) || !isTerminal(os.Stderr) {
+		return nil
+	}
+	return pickNote
+}
+
+// configFile returns the config file used, or the default location when
+// none was read.
+func configFile() string {
+	if file := viper.ConfigFileUsed(); file != "" {
+		return file
+	}
+	return filepath.Join(configDir(), "config.yaml")
+}
+
+// updateConfigFile loads the config file, lets fn modify its settings and
+// writes the result back.
+func updateConfigFile(fn func(settings map[string]interface{}) error) error {
+	file := configFile()
+	settings := make(map[string]interface{})
+	data, err := ioutil.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := yaml.Unmarshal(data, &settings); err != nil {
+		return err
+	}
+	if err := fn(settings); err != nil {
+		return err
+	}
+	data, err = yaml.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, data, 0644)
+}
+
+// expandHome replaces a leading ~ in paths read from the config file.
+func expandHome(dir string) string {
+	if dir != "~" && !strings.HasPrefix(dir, "~/") {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return dir
+	}
+	return filepath.Join(home, dir[1:])
 }
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"context"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/rameshg87/tools/note/lib"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve a web UI and a REST API for the notes",
+	Long: `Serve a web UI for browsing, searching and editing the notes of the
+selected notebook, and the REST API it uses:
+
+  GET    /api/notes?q=name&tag=tag  list notes
+  GET    /api/search?q=pattern      search notes
+  GET    /api/notes/<name>          read a note
+  PUT    /api/notes/<name>          create or replace a note
+  DELETE /api/notes/<name>          move a note to the trash
+
+Replacing or deleting a note requires the ETag it was read with in an
+If-Match header. With --token, or serve.token in the config file, API
+requests must send "Authorization: Bearer <token>". Without a token only
+requests addressed to localhost or a loopback address are answered.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		addr := viper.GetString("serve.addr")
+		token := viper.GetString("serve.token")
+		if host, _, err := net.SplitHostPort(addr); err == nil && token == "" {
+			if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+				log.Print("note: Without a token only requests to a loopback address are answered, set --token to serve other hosts")
+			}
+		}
+		server := &http.Server{
+			Addr:              addr,
+			Handler:           lib.NewServer(lib.ServerOptions{Addr: addr, Token: token}),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			server.Shutdown(shutdownCtx)
+		}()
+		log.Print("note: Serving on http://" + addr)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(serveCmd)
+	serveCmd.Flags().String("addr", "127.0.0.1:8080", "address to listen on")
+	serveCmd.Flags().String("token", "", "token API requests must send")
+	viper.BindPFlag("serve.addr", serveCmd.Flags().Lookup("addr"))
+	viper.BindPFlag("serve.token", serveCmd.Flags().Lookup("token"))
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"bytes"
+	"log"
+	"os"
+
+	"github.com/charmbracelet/glamour"
+	"github.com/rameshg87/tools/note/lib"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var showRaw bool
+var showNoPager bool
+
+// maxShowWidth keeps rendered notes readable on wide terminals.
+const maxShowWidth = 100
+
+// showCmd represents the show command
+var showCmd = &cobra.Command{
+	Use:   "show <name>",
+	Short: "Show a note rendered in the terminal",
+	Long: `Show the note matching name, with its markdown rendered for the
+terminal, without opening the editor. Output longer than the terminal goes
+through $PAGER. The colors follow show.style in the config file: dark,
+light, dracula, pink, ascii or notty.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			log.Fatal("note: No filename provided")
+		}
+		requireText("show")
+		_, data, err := lib.Read(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		text := string(data)
+		if !showRaw {
+			if text, err = renderMarkdown(data); err != nil {
+				log.Fatal(err)
+			}
+		}
+		if showNoPager {
+			_, err = os.Stdout.WriteString(text)
+		} else {
+			err = page(text)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// renderMarkdown renders a note for the terminal, showing its title in
+// place of its front matter.
+func renderMarkdown(data []byte) (string, error) {
+	meta, body := lib.SplitFrontMatter(data)
+	if meta.Title != "" && !bytes.HasPrefix(bytes.TrimLeft(body, "\n"), []byte("# ")) {
+		body = append([]byte("# "+meta.Title+"\n\n"), body...)
+	}
+	style := viper.GetString("show.style")
+	if !useColor() {
+		style = "notty"
+	}
+	width, _ := terminalSize(os.Stdout)
+	if width > maxShowWidth {
+		width = maxShowWidth
+	}
+	renderer, err := glamour.NewTermRenderer(
+		glamour.WithStandardStyle(style),
+		glamour.WithWordWrap(width-4),
+	)
+	if err != nil {
+		return "", err
+	}
+	return renderer.Render(string(body))
+}
+
+func init() {
+	RootCmd.AddCommand(showCmd)
+	showCmd.Flags().BoolVar(&showRaw, "raw", false, "print the note as it is")
+	showCmd.Flags().BoolVar(&showNoPager, "no-pager", false, "don't page long output")
+}
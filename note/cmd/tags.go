@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/rameshg87/tools/note/lib"
+	"github.com/spf13/cobra"
+)
+
+// tagsCmd represents the tags command
+var tagsCmd = &cobra.Command{
+	Use:   "tags",
+	Short: "List the tags used in notes with their counts",
+	Run: func(cmd *cobra.Command, args []string) {
+		tags, err := lib.Tags()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if out := newRecordWriter(); out != nil {
+			for _, tag := range tags {
+				out.write(tag, []string{tag.Tag, strconv.Itoa(tag.Count)})
+			}
+			out.close()
+			return
+		}
+		for _, tag := range tags {
+			fmt.Printf("%d\t%s\n", tag.Count, tag.Tag)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(tagsCmd)
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/rameshg87/tools/note/lib"
+	"github.com/spf13/cobra"
+)
+
+// templateCmd represents the template command
+var templateCmd = &cobra.Command{
+	Use:   "template",
+	Short: "Manage the templates new notes are created from",
+	Long: `Manage the templates new notes are created from.
+
+Templates use Go's text/template syntax. {{.Date}}, {{.Time}},
+{{.Title}}, {{.User}} and {{.Notebook}} are always available and
+{{var "name"}} asks for a custom value, unless given with --var.`,
+}
+
+// templateRecord is a template for machine-readable output.
+type templateRecord struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+// templateLsCmd represents the template ls command
+var templateLsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List templates",
+	Run: func(cmd *cobra.Command, args []string) {
+		names, err := lib.ListTemplates()
+		if err != nil {
+			log.Fatal(err)
+		}
+		out := newRecordWriter()
+		for _, name := range names {
+			if out != nil {
+				file, _ := lib.TemplateFile(name)
+				record := templateRecord{name, file}
+				out.write(record, []string{record.Path, record.Name})
+				continue
+			}
+			fmt.Println(name)
+		}
+		if out != nil {
+			out.close()
+		}
+	},
+}
+
+// templateShowCmd represents the template show command
+var templateShowCmd = &cobra.Command{
+	Use:   "show <name>",
+	Short: "Print a template",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			log.Fatal("note: No template provided")
+		}
+		requireText("template show")
+		file, err := lib.TemplateFile(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			log.Fatal(err)
+		}
+		os.Stdout.Write(data)
+	},
+}
+
+// templateEditCmd represents the template edit command
+var templateEditCmd = &cobra.Command{
+	Use:   "edit <name>",
+	Short: "Edit or create a template",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			log.Fatal("note: No template provided")
+		}
+		if err := lib.EditTemplate(args[0]); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var stdinReader = bufio.NewReader(os.Stdin)
+
+// promptVar asks for the value of a custom template variable on stdin.
+func promptVar(name string) (string, error) {
+	fmt.Fprintf(os.Stderr, "%s: ", name)
+	value, err := stdinReader.ReadString('\n')
+	if err != nil && value == "" {
+		return "", err
+	}
+	return strings.TrimRight(value, "\r\n"), nil
+}
+
+func init() {
+	RootCmd.AddCommand(templateCmd)
+	templateCmd.AddCommand(templateLsCmd)
+	templateCmd.AddCommand(templateShowCmd)
+	templateCmd.AddCommand(templateEditCmd)
+}
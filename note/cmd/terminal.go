@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// isTerminal reports whether f is connected to a terminal.
+func isTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// useColor reports whether output to stdout should be colored.
+func useColor() bool {
+	return os.Getenv("NO_COLOR") == "" && isTerminal(os.Stdout)
+}
+
+const (
+	colorReset   = "\x1b[0m"
+	colorMatch   = "\x1b[1;31m"
+	colorFile    = "\x1b[35m"
+	colorLineNum = "\x1b[32m"
+)
+
+// terminalSize returns the size of the terminal f is connected to, or 80
+// columns and 0 lines when it isn't one.
+func terminalSize(f *os.File) (int, int) {
+	width, height, err := term.GetSize(int(f.Fd()))
+	if err != nil || width <= 0 {
+		return 80, 0
+	}
+	return width, height
+}
+
+// page prints text to stdout through $PAGER when stdout is a terminal the
+// text doesn't fit in, and directly otherwise.
+func page(text string) error {
+	_, height := terminalSize(os.Stdout)
+	if height == 0 || strings.Count(text, "\n") < height {
+		_, err := io.WriteString(os.Stdout, text)
+		return err
+	}
+	pager := strings.Fields(os.Getenv("PAGER"))
+	if len(pager) == 0 {
+		pager = []string{"less"}
+	}
+	cmd := exec.Command(pager[0], pager[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	// Like git, let less pass colors through and quit on short output.
+	if os.Getenv("LESS") == "" {
+		cmd.Env = append(os.Environ(), "LESS=FRX")
+	}
+	if err := cmd.Run(); err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			return err
+		}
+		// The pager is missing, print without one.
+		_, err = io.WriteString(os.Stdout, text)
+		return err
+	}
+	return nil
+}
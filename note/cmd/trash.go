@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/rameshg87/tools/note/lib"
+	"github.com/spf13/cobra"
+)
+
+var trashOlderThan string
+
+// trashRecord is a note in the trash for machine-readable output. Path is
+// where it was and File where it is in the trash.
+type trashRecord struct {
+	ID      string    `json:"id"`
+	Path    string    `json:"path"`
+	Deleted time.Time `json:"deleted"`
+	File    string    `json:"file"`
+}
+
+// rmCmd represents the rm command
+var rmCmd = &cobra.Command{
+	Use:   "rm <name>",
+	Short: "Move a note to the trash",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			log.Fatal("note: No filename provided")
+		}
+		if _, err := lib.Remove(args[0]); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// trashCmd represents the trash command
+var trashCmd = &cobra.Command{
+	Use:   "trash",
+	Short: "Manage deleted notes",
+}
+
+// trashLsCmd represents the trash ls command
+var trashLsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List notes in the trash",
+	Run: func(cmd *cobra.Command, args []string) {
+		entries, err := lib.ListTrash()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if out := newRecordWriter(); out != nil {
+			for _, entry := range entries {
+				record := trashRecord{entry.ID, entry.Path, entry.Deleted, entry.File}
+				out.write(record, []string{record.File, record.ID, record.Path, formatTime(record.Deleted)})
+			}
+			out.close()
+			return
+		}
+		for _, entry := range entries {
+			fmt.Printf("%s\t%s\t%s\n", entry.Deleted.Format("2006-01-02 15:04"), entry.ID, entry.Path)
+		}
+	},
+}
+
+// trashRestoreCmd represents the trash restore command
+var trashRestoreCmd = &cobra.Command{
+	Use:   "restore <name>",
+	Short: "Move a note from the trash back to where it was",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			log.Fatal("note: No filename provided")
+		}
+		if _, err := lib.RestoreTrash(args[0]); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// trashEmptyCmd represents the trash empty command
+var trashEmptyCmd = &cobra.Command{
+	Use:   "empty",
+	Short: "Permanently delete notes from the trash",
+	Long: `Permanently delete the notes that have been in the trash for longer
+than --older-than, 30 days by default. Use --older-than 0s to delete
+every note in the trash.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		age, err := lib.ParseAge(trashOlderThan)
+		if err != nil {
+			log.Fatal(err)
+		}
+		removed, err := lib.EmptyTrash(age)
+		for _, entry := range removed {
+			fmt.Println(entry.Path)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(rmCmd)
+	RootCmd.AddCommand(trashCmd)
+	trashCmd.AddCommand(trashLsCmd)
+	trashCmd.AddCommand(trashRestoreCmd)
+	trashCmd.AddCommand(trashEmptyCmd)
+	trashEmptyCmd.Flags().StringVar(&trashOlderThan, "older-than", "30d", "only delete notes deleted longer ago than this, e.g. 30d")
+}
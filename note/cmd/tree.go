@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strconv"
+
+	"github.com/rameshg87/tools/note/lib"
+	"github.com/spf13/cobra"
+)
+
+var treeNotes bool
+
+// folderRecord is a folder for machine-readable output.
+type folderRecord struct {
+	Path  string `json:"path"`
+	Name  string `json:"name"`
+	Notes int    `json:"notes"`
+	Total int    `json:"total"`
+}
+
+// treeCmd represents the tree command
+var treeCmd = &cobra.Command{
+	Use:   "tree",
+	Short: "Show the folders of the notes directory",
+	Long: `Show the folders of the notes directory as a tree, each with the
+number of notes in it and its subfolders.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		tree, err := lib.Tree()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if out := newRecordWriter(); out != nil {
+			tree.Walk(func(node *lib.TreeNode) {
+				record := folderRecord{node.Path, node.Name, node.Notes, node.Total}
+				out.write(record, []string{record.Path, record.Name, strconv.Itoa(record.Notes), strconv.Itoa(record.Total)})
+			})
+			out.close()
+			return
+		}
+		fmt.Printf(". (%d)\n", tree.Total)
+		printTree(tree, "")
+	},
+}
+
+// printTree prints the subfolders of node, and its notes with --notes,
+// below it the way tree(1) does.
+func printTree(node *lib.TreeNode, indent string) {
+	var files []string
+	if treeNotes {
+		files = node.Files
+	}
+	n := len(node.Children) + len(files)
+	for i, child := range node.Children {
+		branch, next := "├── ", "│   "
+		if i == n-1 {
+			branch, next = "└── ", "    "
+		}
+		fmt.Printf("%s%s%s/ (%d)\n", indent, branch, filepath.Base(child.Path), child.Total)
+		printTree(child, indent+next)
+	}
+	for i, file := range files {
+		branch := "├── "
+		if len(node.Children)+i == n-1 {
+			branch = "└── "
+		}
+		fmt.Printf("%s%s%s\n", indent, branch, filepath.Base(file))
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(treeCmd)
+	treeCmd.Flags().BoolVarP(&treeNotes, "notes", "n", false, "show the notes of every folder too")
+}
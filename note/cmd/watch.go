@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"github.com/rameshg87/tools/note/lib"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// watchCmd represents the watch command
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Keep the notes, tags, links and search index of a notebook cached",
+	Long: `Follow the changes made to the selected notebook, by note or any
+other program, and keep the list of notes, their tags and links, the
+search index and the access history up to date.
+
+Bursts of changes, like the ones editors make when saving, are handled
+once they settle for --debounce. Notes renamed outside of note keep
+their access history.
+
+While it runs, ls, tags, links, grep and the other commands ask it over
+a Unix socket in the notebook instead of reading every note. Set
+watch.use_daemon to false in the config file to stop them from doing so.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		root, err := lib.GetConfig().Root()
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Print("note: Watching " + root)
+		if err := lib.Watch(ctx, viper.GetDuration("watch.debounce")); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// watchStatusCmd represents the watch status command
+var watchStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show whether a watch daemon is running for the notebook",
+	Run: func(cmd *cobra.Command, args []string) {
+		status, err := lib.Watching()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if status == nil {
+			log.Fatal("note: No watch daemon is running")
+		}
+		if out := newRecordWriter(); out != nil {
+			out.write(status, []string{
+				status.Root,
+				strconv.Itoa(status.Pid),
+				strconv.Itoa(status.Notes),
+				formatTime(status.Started),
+				formatTime(status.Updated),
+			})
+			out.close()
+			return
+		}
+		fmt.Printf("Watching %s (pid %d)\n", status.Root, status.Pid)
+		fmt.Printf("%d notes, updated %s\n", status.Notes, status.Updated.Format("2006-01-02 15:04:05"))
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(watchCmd)
+	watchCmd.AddCommand(watchStatusCmd)
+	watchCmd.Flags().Duration("debounce", lib.DefaultDebounce, "time changes must settle for before an update")
+	viper.BindPFlag("watch.debounce", watchCmd.Flags().Lookup("debounce"))
+}
@@ -0,0 +1,177 @@
+package lib
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Opening a note is recorded in the state directory of its notebook, since
+// atime is unreliable on noatime and relatime mounts and synced folders.
+const accessFile = "access.json"
+
+// Access is how often and when a note was last opened, keyed by its path
+// relative to the notebook root.
+type Access struct {
+	Count int       `json:"count"`
+	Last  time.Time `json:"last"`
+}
+
+type accessLog map[string]*Access
+
+func accessPath(root string) string {
+	return filepath.Join(root, stateDir, accessFile)
+}
+
+// loadAccess returns the access log of the notebook rooted at root, which
+// is empty when none was written yet.
+func loadAccess(root string) accessLog {
+	l := make(accessLog)
+	data, err := ioutil.ReadFile(accessPath(root))
+	if err != nil {
+		return l
+	}
+	json.Unmarshal(data, &l)
+	return l
+}
+
+func (l accessLog) save(root string) error {
+	data, err := json.Marshal(l)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Join(root, stateDir), 0755); err != nil {
+		return err
+	}
+	return writeFileAtomic(accessPath(root), data)
+}
+
+// updateLog applies fn to the access log of the notebook rooted at root,
+// and saves it when fn reports a change. The notebook is locked meanwhile,
+// so concurrent updates don't lose each other's changes.
+func updateLog(root string, fn func(l accessLog) bool) error {
+	unlock, err := lockNotebook(root)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	l := loadAccess(root)
+	if !fn(l) {
+		return nil
+	}
+	return l.save(root)
+}
+
+// updateAccess applies fn to the access log of the notebook file is in.
+func updateAccess(file string, fn func(l accessLog, rel string)) error {
+	root, err := rootOf(file)
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(root, file)
+	if err != nil {
+		return err
+	}
+	return updateLog(root, func(l accessLog) bool {
+		fn(l, filepath.ToSlash(rel))
+		return true
+	})
+}
+
+// recordAccess notes that file was just opened.
+func recordAccess(file string) error {
+	return updateAccess(file, func(l accessLog, rel string) {
+		if l[rel] == nil {
+			l[rel] = &Access{}
+		}
+		l[rel].Count++
+		l[rel].Last = time.Now()
+	})
+}
+
+// moveAccess carries the access history of a note over to its new name,
+// or forgets it when newFile is empty.
+func moveAccess(root, file, newFile string) error {
+	rel, err := filepath.Rel(root, file)
+	if err != nil {
+		return err
+	}
+	newRel := ""
+	if newFile != "" {
+		if newRel, err = filepath.Rel(root, newFile); err != nil {
+			return err
+		}
+	}
+	return updateLog(root, func(l accessLog) bool {
+		access, ok := l[filepath.ToSlash(rel)]
+		if !ok {
+			return false
+		}
+		delete(l, filepath.ToSlash(rel))
+		if newRel != "" {
+			l[filepath.ToSlash(newRel)] = access
+		}
+		return true
+	})
+}
+
+// frecency weighs how often a note was opened by how recently, so notes
+// opened a lot a while ago give way to the ones in use now.
+func (a *Access) frecency(now time.Time) float64 {
+	if a == nil {
+		return 0
+	}
+	age := now.Sub(a.Last)
+	weight := 0.25
+	switch {
+	case age < time.Hour:
+		weight = 4
+	case age < 24*time.Hour:
+		weight = 2
+	case age < 7*24*time.Hour:
+		weight = 1
+	case age < 30*24*time.Hour:
+		weight = 0.5
+	}
+	return float64(a.Count) * weight
+}
+
+// lastUsed returns when a note was last opened or modified, whichever is
+// later.
+func (a *Access) lastUsed(file string) time.Time {
+	last := time.Unix(Mtime(file), 0)
+	if a != nil && a.Last.After(last) {
+		return a.Last
+	}
+	return last
+}
+
+// RecentNote is a note of the selected notebook that has been opened.
+type RecentNote struct {
+	File string
+	Access
+}
+
+// Recent returns the notes of the selected notebook that have been opened,
+// least recently opened first like List.
+func Recent() ([]RecentNote, error) {
+	root, err := GetConfig().Root()
+	if err != nil {
+		return nil, err
+	}
+	var notes []RecentNote
+	for rel, access := range loadAccess(root) {
+		file := filepath.Join(root, filepath.FromSlash(rel))
+		if _, err := os.Stat(file); err != nil {
+			continue
+		}
+		notes = append(notes, RecentNote{file, *access})
+	}
+	sort.Slice(notes, func(i, j int) bool {
+		return notes[i].Last.Before(notes[j].Last)
+	})
+	return notes, nil
+}
@@ -0,0 +1,100 @@
+package lib
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccess(t *testing.T) {
+	dir, _ := createTestFiles()
+	defer os.RemoveAll(dir)
+	SetConfig(&Config{NotesDir: dir, Editor: "true", Sort: SortRecent})
+	defer SetConfig(nil)
+	var files []string
+	for i, name := range []string{"a.md", "b.md", "c.md"} {
+		file := path.Join(dir, name)
+		ioutil.WriteFile(file, []byte(name), 0644)
+		mtime := time.Now().Add(time.Duration(i-10) * time.Hour)
+		os.Chtimes(file, mtime, mtime)
+		files = append(files, file)
+	}
+
+	// Notes never opened are ordered by mtime, opened ones by when they
+	// were last opened.
+	returnedFileNames, _ := List(".md")
+	assert.Equal(t, files, returnedFileNames)
+	assert.Nil(t, Edit("a.md", false))
+	returnedFileNames, _ = List(".md")
+	assert.Equal(t, []string{files[1], files[2], files[0]}, returnedFileNames)
+	recent, err := Recent()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(recent))
+	assert.Equal(t, 1, recent[0].Count)
+
+	// Frecency favors notes opened often.
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, Edit("b.md", false))
+	}
+	assert.Nil(t, Edit("c.md", false))
+	SetConfig(&Config{NotesDir: dir, Editor: "true", Sort: SortFrecency})
+	returnedFileNames, _ = List(".md")
+	assert.Equal(t, []string{files[0], files[2], files[1]}, returnedFileNames)
+
+	// Moving and removing notes carries their history along.
+	_, err = Move("b.md", "d.md", false)
+	assert.Nil(t, err)
+	_, err = Remove("c.md")
+	assert.Nil(t, err)
+	recent, _ = Recent()
+	assert.Equal(t, 2, len(recent))
+	assert.Equal(t, path.Join(dir, "d.md"), recent[1].File)
+	assert.Equal(t, 3, recent[1].Count)
+}
+
+func TestSortSizeCreated(t *testing.T) {
+	dir, _ := createTestFiles()
+	defer os.RemoveAll(dir)
+	small := path.Join(dir, "small.md")
+	large := path.Join(dir, "large.md")
+	ioutil.WriteFile(small, []byte("---\ncreated: 2024-01-02\n---\n"), 0644)
+	ioutil.WriteFile(large, []byte("---\ncreated: 2023-01-02\n---\nmore text\n"), 0644)
+
+	SetConfig(&Config{NotesDir: dir, Sort: SortSize})
+	defer SetConfig(nil)
+	returnedFileNames, _ := List(".md")
+	assert.Equal(t, []string{small, large}, returnedFileNames)
+	SetConfig(&Config{NotesDir: dir, Sort: SortCreated})
+	returnedFileNames, _ = List(".md")
+	assert.Equal(t, []string{large, small}, returnedFileNames)
+}
+
+func TestAtimeMissingFile(t *testing.T) {
+	assert.Equal(t, int64(0), Atime("/nonexistent/note"))
+}
+
+func TestAccessConcurrent(t *testing.T) {
+	dir, files := createTestFiles()
+	defer os.RemoveAll(dir)
+	SetConfig(&Config{NotesDir: dir})
+	defer SetConfig(nil)
+
+	// Notes opened at the same time are all counted.
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			assert.Nil(t, recordAccess(files[i%2]))
+		}(i)
+	}
+	wg.Wait()
+	access := loadAccess(dir)
+	assert.Equal(t, 10, access[path.Base(files[0])].Count)
+	assert.Equal(t, 10, access[path.Base(files[1])].Count)
+}
@@ -8,7 +8,10 @@ import (
 )
 
 func Atime(filename string) int64 {
-	fi, _ := os.Stat(filename)
+	fi, err := os.Stat(filename)
+	if err != nil {
+		return 0
+	}
 	stat := fi.Sys().(*syscall.Stat_t)
 	return stat.Atim.Sec
 }
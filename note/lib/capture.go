@@ -0,0 +1,103 @@
+package lib
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultInbox is the note entries are appended to when no name is given.
+const DefaultInbox = "inbox.md"
+
+const entryTimeLayout = "2006-01-02 15:04"
+
+type EmptyEntryError bool
+
+func (e EmptyEntryError) Error() string {
+	return "Nothing to append."
+}
+
+// appendTarget returns the note name refers to for Append. A name that is
+// the exact path of a note picks it even when other notes contain it too,
+// and a name matching no note is the path of a new one.
+func appendTarget(root, name string) (string, error) {
+	file, err := notePath(root, name)
+	if err != nil {
+		return "", err
+	}
+	if fi, err := os.Stat(file); err == nil && !fi.IsDir() {
+		return file, nil
+	}
+	matchingFiles, err := list(name, false)
+	if err != nil {
+		return "", err
+	}
+	match, err := single(matchingFiles)
+	if _, ok := err.(NoFilesError); ok {
+		return file, nil
+	}
+	return match, err
+}
+
+// Append adds text to the note matching name as an entry headed by the
+// current time, creating the note when none matches. An empty name stands
+// for the inbox note. Appends hold the notebook lock and replace the note
+// atomically, so concurrent ones don't lose entries.
+func Append(name, text string) (string, error) {
+	conf := GetConfig()
+	text = strings.Trim(text, "\r\n")
+	if strings.TrimSpace(text) == "" {
+		return "", EmptyEntryError(true)
+	}
+	if name == "" {
+		name = conf.Inbox
+		if name == "" {
+			name = DefaultInbox
+		}
+	}
+	root, err := conf.Root()
+	if err != nil {
+		return "", err
+	}
+	unlock, err := lockNotebook(root)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	file, err := appendTarget(root, name)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return "", err
+	}
+	var k *keys
+	if IsEncrypted(file) {
+		if k, err = loadKeys(); err != nil {
+			return "", err
+		}
+	}
+	var content string
+	if _, err := os.Stat(file); err == nil {
+		data, err := readNote(file, k)
+		if err != nil {
+			return "", err
+		}
+		if content = strings.TrimRight(string(data), "\n"); content != "" {
+			content += "\n\n"
+		}
+	}
+	content += "## " + time.Now().Format(entryTimeLayout) + "\n\n" + text + "\n"
+	if err := writeNote(file, []byte(content), k); err != nil {
+		return "", err
+	}
+	if conf.AutoCommit {
+		if err := commitNote(file, "Append to %s"); err != nil {
+			log.Print(err)
+		}
+	}
+	return file, nil
+}
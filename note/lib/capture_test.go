@@ -0,0 +1,69 @@
+package lib
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAppend(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "note")
+	defer os.RemoveAll(dir)
+	SetConfig(&Config{NotesDir: dir})
+	defer SetConfig(nil)
+
+	// Entries go to the inbox by default, which is created.
+	file, err := Append("", "call bob\n")
+	assert.Nil(t, err)
+	assert.Equal(t, path.Join(dir, DefaultInbox), file)
+	data, _ := ioutil.ReadFile(file)
+	lines := strings.Split(string(data), "\n")
+	assert.True(t, strings.HasPrefix(lines[0], "## "))
+	assert.Equal(t, []string{"", "call bob", ""}, lines[1:])
+
+	// Later entries are separated by a blank line.
+	_, err = Append("inbox", "buy milk")
+	assert.Nil(t, err)
+	data, _ = ioutil.ReadFile(file)
+	assert.Regexp(t, `^## [-0-9]{10} [0-9:]{5}\n\ncall bob\n\n## [-0-9]{10} [0-9:]{5}\n\nbuy milk\n$`, string(data))
+
+	// An exact path wins over other notes containing it, and missing
+	// folders are created.
+	ioutil.WriteFile(path.Join(dir, "old-inbox.md"), []byte(""), 0644)
+	file, err = Append("inbox.md", "x")
+	assert.Nil(t, err)
+	assert.Equal(t, path.Join(dir, DefaultInbox), file)
+	file, err = Append("logs/build.md", "ok")
+	assert.Nil(t, err)
+	assert.Equal(t, path.Join(dir, "logs", "build.md"), file)
+
+	_, err = Append("", " \n")
+	assert.Equal(t, EmptyEntryError(true), err)
+	_, err = Append("../x", "text")
+	assert.Equal(t, InvalidNameError("../x"), err)
+}
+
+func TestAppendConcurrent(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "note")
+	defer os.RemoveAll(dir)
+	SetConfig(&Config{NotesDir: dir})
+	defer SetConfig(nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := Append("", "entry")
+			assert.Nil(t, err)
+		}()
+	}
+	wg.Wait()
+	data, _ := ioutil.ReadFile(path.Join(dir, DefaultInbox))
+	assert.Equal(t, 20, strings.Count(string(data), "\nentry\n"))
+}
@@ -0,0 +1,179 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CleanRule selects files removed by Clean. A file matches when its base
+// name matches Glob or Regex and all the given age and size conditions
+// hold. MinAge is the time since the last modification, like "1h" or "7d".
+type CleanRule struct {
+	Glob    string `mapstructure:"glob"`
+	Regex   string `mapstructure:"regex"`
+	MinAge  string `mapstructure:"min_age"`
+	MinSize int64  `mapstructure:"min_size"`
+	MaxSize int64  `mapstructure:"max_size"`
+}
+
+var DefaultCleanRules = []CleanRule{
+	// vim swap files
+	{Glob: ".*.sw[a-p]"},
+	// emacs auto-save, lock and backup files
+	{Glob: "#*#"},
+	{Glob: ".#*"},
+	{Glob: "*~"},
+	// desktop metadata
+	{Glob: ".DS_Store"},
+	{Glob: "desktop.ini"},
+	{Glob: "Thumbs.db"},
+}
+
+type InvalidCleanRuleError struct {
+	Rule CleanRule
+	Err  error
+}
+
+func (e *InvalidCleanRuleError) Error() string {
+	return "Invalid clean rule: " + e.Err.Error()
+}
+
+// CleanFailure is a file Clean failed to remove.
+type CleanFailure struct {
+	File string
+	Err  error
+}
+
+type CleanResult struct {
+	Removed []string
+	// Records describe the removed files as they were before removal.
+	Records  []*NoteRecord
+	Size     int64
+	Failures []CleanFailure
+}
+
+type CleanError []CleanFailure
+
+func (e CleanError) Error() string {
+	var msgs []string
+	for _, failure := range e {
+		msgs = append(msgs, failure.Err.Error())
+	}
+	return "Failed to remove " + strconv.Itoa(len(e)) + " files:\n" + strings.Join(msgs, "\n")
+}
+
+type cleanMatcher struct {
+	rule   CleanRule
+	re     *regexp.Regexp
+	minAge time.Duration
+}
+
+func (m *cleanMatcher) match(name string, fi os.FileInfo, now time.Time) bool {
+	base := filepath.Base(name)
+	matched := false
+	if m.rule.Glob != "" {
+		matched, _ = filepath.Match(m.rule.Glob, base)
+	}
+	if !matched && m.re != nil {
+		matched = m.re.MatchString(base)
+	}
+	if !matched {
+		return false
+	}
+	if m.minAge > 0 && now.Sub(fi.ModTime()) < m.minAge {
+		return false
+	}
+	if m.rule.MinSize > 0 && fi.Size() < m.rule.MinSize {
+		return false
+	}
+	if m.rule.MaxSize > 0 && fi.Size() > m.rule.MaxSize {
+		return false
+	}
+	return true
+}
+
+func cleanMatchers() ([]*cleanMatcher, error) {
+	conf := GetConfig()
+	rules := append([]CleanRule{}, conf.CleanRules...)
+	for _, pattern := range conf.CleanPatterns {
+		rules = append(rules, CleanRule{Regex: pattern})
+	}
+	var matchers []*cleanMatcher
+	for _, rule := range rules {
+		m := &cleanMatcher{rule: rule}
+		if rule.Glob != "" {
+			if _, err := filepath.Match(rule.Glob, ""); err != nil {
+				return nil, &InvalidCleanRuleError{rule, err}
+			}
+		}
+		if rule.Regex != "" {
+			re, err := regexp.Compile(rule.Regex)
+			if err != nil {
+				return nil, &InvalidCleanRuleError{rule, err}
+			}
+			m.re = re
+		}
+		if rule.MinAge != "" {
+			age, err := ParseAge(rule.MinAge)
+			if err != nil {
+				return nil, &InvalidCleanRuleError{rule, err}
+			}
+			m.minAge = age
+		}
+		matchers = append(matchers, m)
+	}
+	return matchers, nil
+}
+
+func Clean() error {
+	_, err := CleanWith(false)
+	return err
+}
+
+// CleanWith removes the files matching the clean rules and returns them.
+// With dryRun nothing is removed. Files that couldn't be removed are
+// returned in the result and as a CleanError.
+func CleanWith(dryRun bool) (*CleanResult, error) {
+	files, err := List("")
+	if err != nil {
+		return nil, err
+	}
+	matchers, err := cleanMatchers()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	result := &CleanResult{}
+	for _, name := range files {
+		fi, err := os.Lstat(name)
+		if err != nil {
+			continue
+		}
+		for _, m := range matchers {
+			if !m.match(name, fi, now) {
+				continue
+			}
+			record, recordErr := NewNoteRecord(name)
+			if !dryRun {
+				if err := os.Remove(name); err != nil {
+					result.Failures = append(result.Failures, CleanFailure{name, err})
+					break
+				}
+			}
+			result.Removed = append(result.Removed, name)
+			if recordErr == nil {
+				result.Records = append(result.Records, record)
+			}
+			result.Size += fi.Size()
+			break
+		}
+	}
+	if len(result.Failures) > 0 {
+		return result, CleanError(result.Failures)
+	}
+	return result, nil
+}
@@ -0,0 +1,94 @@
+package lib
+
+import (
+	"os"
+)
+
+const (
+	SortAtime    = "atime"
+	SortRecent   = "recent"
+	SortFrecency = "frecency"
+	SortMtime    = "mtime"
+	SortName     = "name"
+	SortSize     = "size"
+	SortCreated  = "created"
+)
+
+// Config holds the resolved settings used by the lib functions. The cmd
+// package builds it from the config file, the environment and the flags
+// and installs it with SetConfig.
+type Config struct {
+	NotesDir   string
+	Notebook   string
+	Notebooks  map[string]string
+	Editor     string
+	Ignore     []string
+	Sort       string
+	CleanRules []CleanRule
+	// CleanPatterns are regular expressions of base names removed by
+	// Clean in addition to CleanRules.
+	CleanPatterns []string
+	// AutoCommit commits every edited note to a git repository kept in
+	// the root of its notebook.
+	AutoCommit bool
+	// IdentityFile holds the age identities used for encrypted notes and
+	// PassphraseFile an optional passphrase for passphrase encrypted ones.
+	IdentityFile   string
+	PassphraseFile string
+	// TemplatesDir overrides the templates directory of the notebook and
+	// DefaultTemplates maps directories, relative to the notebook root, to
+	// the template used for new notes created in them.
+	TemplatesDir     string
+	DefaultTemplates map[string]string
+	// JournalLayout is where journal entries are kept, with YYYY, MM and
+	// DD standing for the date of the entry, and JournalTemplate the
+	// template new entries are created from.
+	JournalLayout   string
+	JournalTemplate string
+	// Inbox is the note Append adds to when no name is given, DefaultInbox
+	// when empty.
+	Inbox string
+	// UseDaemon lets the lib functions ask a running watch daemon for the
+	// notes, their tags and links and the search candidates, instead of
+	// reading the notebook.
+	UseDaemon bool
+	// Choose picks one of several notes matching a name. Without it an
+	// ambiguous name is a MultipleFilesError.
+	Choose func(files []string) (string, error)
+}
+
+var config *Config
+
+// Root returns the directory of the selected notebook, or NotesDir when no
+// notebook was selected.
+func (c *Config) Root() (string, error) {
+	if c.Notebook != "" {
+		dir, ok := c.Notebooks[c.Notebook]
+		if !ok {
+			return "", NotebookNotFoundError(c.Notebook)
+		}
+		return dir, nil
+	}
+	if c.NotesDir == "" {
+		return "", NoteDirNotSetError(true)
+	}
+	return c.NotesDir, nil
+}
+
+func SetConfig(c *Config) {
+	config = c
+}
+
+// GetConfig returns the config installed with SetConfig, or one built from
+// the NOTES_DIR and EDITOR environment variables when none was installed.
+func GetConfig() *Config {
+	if config != nil {
+		return config
+	}
+	return &Config{
+		NotesDir:   os.Getenv("NOTES_DIR"),
+		Editor:     os.Getenv("EDITOR"),
+		Sort:       SortAtime,
+		CleanRules: DefaultCleanRules,
+	}
+}
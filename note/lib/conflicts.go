@@ -0,0 +1,282 @@
+package lib
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Conflict copies created by sync tools. The submatches are the parts of
+// the name of the original note.
+var conflictPatterns = []struct {
+	kind  string
+	regex *regexp.Regexp
+}{
+	{"dropbox", regexp.MustCompile(`^(.+?) \([^()]*conflicted copy[^()]*\)(\.[^.]+)?$`)},
+	{"syncthing", regexp.MustCompile(`^(.+?)\.sync-conflict-\d{8}-\d{6}(?:-[A-Z0-9]+)?(\.[^.]+)?$`)},
+	{"drive", regexp.MustCompile(`^(.+?) \(\d+\)(\.[^.]+)?$`)},
+}
+
+// Conflict is a conflict copy of a note. Orphan is set when the original
+// note doesn't exist anymore.
+type Conflict struct {
+	Kind     string `json:"kind"`
+	Copy     string `json:"copy"`
+	Original string `json:"original"`
+	Orphan   bool   `json:"orphan"`
+}
+
+// conflictOf returns the conflict file is a copy in, or nil when it isn't
+// a conflict copy. Names like "foo (1).md" are common enough that they
+// only count as conflict copies when the original exists.
+func conflictOf(file string) *Conflict {
+	base := filepath.Base(file)
+	for _, pattern := range conflictPatterns {
+		m := pattern.regex.FindStringSubmatch(base)
+		if m == nil {
+			continue
+		}
+		original := filepath.Join(filepath.Dir(file), m[1]+m[2])
+		fi, err := os.Stat(original)
+		orphan := err != nil || fi.IsDir()
+		if orphan && pattern.kind == "drive" {
+			return nil
+		}
+		return &Conflict{pattern.kind, file, original, orphan}
+	}
+	return nil
+}
+
+// Conflicts returns the sync conflict copies in the selected notebook.
+func Conflicts() ([]Conflict, error) {
+	files, err := List("")
+	if err != nil {
+		return nil, err
+	}
+	var conflicts []Conflict
+	for _, file := range files {
+		if conflict := conflictOf(file); conflict != nil {
+			conflicts = append(conflicts, *conflict)
+		}
+	}
+	return conflicts, nil
+}
+
+func splitLines(data []byte) []string {
+	text := strings.TrimSuffix(string(data), "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+// mergeTwoWay merges two versions of a note without a common ancestor.
+// Lines only one side has are kept, and hunks both sides changed are put
+// between conflict markers.
+func mergeTwoWay(original, copy []byte, copyName string) []byte {
+	var out []string
+	for _, h := range diffLines(splitLines(original), splitLines(copy)) {
+		switch {
+		case h.equal || len(h.b) == 0:
+			out = append(out, h.a...)
+		case len(h.a) == 0:
+			out = append(out, h.b...)
+		default:
+			out = append(out, "<<<<<<< original")
+			out = append(out, h.a...)
+			out = append(out, "=======")
+			out = append(out, h.b...)
+			out = append(out, ">>>>>>> "+copyName)
+		}
+	}
+	return []byte(strings.Join(out, "\n") + "\n")
+}
+
+// mergeThreeWay merges two versions of a note with git merge-file, using
+// base as their common ancestor.
+func mergeThreeWay(original, base, copy []byte, copyName string) ([]byte, error) {
+	dir, err := ioutil.TempDir("", "note")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	var files []string
+	for i, data := range [][]byte{original, base, copy} {
+		file := filepath.Join(dir, string(rune('a'+i)))
+		if err := ioutil.WriteFile(file, data, 0600); err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	args := append([]string{"merge-file", "-p", "-L", "original", "-L", "base", "-L", copyName}, files...)
+	merged, err := exec.Command("git", args...).Output()
+	// git merge-file exits with the number of conflicts, up to 127, and
+	// with a higher status when it fails.
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() <= 127 {
+		err = nil
+	}
+	return merged, err
+}
+
+// baseVersion returns the plain text of file in the last commit of the
+// notebook rooted at root made before the time given, which is the version
+// a conflict copy written then was most likely edited from.
+func baseVersion(root, file string, before time.Time, k *keys) ([]byte, error) {
+	if _, err := os.Stat(filepath.Join(root, ".git")); err != nil {
+		return nil, err
+	}
+	rel, err := filepath.Rel(root, file)
+	if err != nil {
+		return nil, err
+	}
+	rel = filepath.ToSlash(rel)
+	hash, err := git(root, "log", "-1", "--format=%H", "--before=@"+strconv.FormatInt(before.Unix(), 10), "--", rel)
+	if err != nil {
+		return nil, err
+	}
+	if hash = strings.TrimSpace(hash); hash == "" {
+		return nil, os.ErrNotExist
+	}
+	data, err := git(root, "show", hash+":"+rel)
+	if err != nil {
+		return nil, err
+	}
+	if !IsEncrypted(file) {
+		return []byte(data), nil
+	}
+	dir, err := ioutil.TempDir("", "note")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	tmp := filepath.Join(dir, filepath.Base(file))
+	if err := ioutil.WriteFile(tmp, []byte(data), 0600); err != nil {
+		return nil, err
+	}
+	return k.decrypt(tmp)
+}
+
+// conflictBase returns the common ancestor of a conflict copy and its
+// original: the original as committed before the copy was last modified.
+func conflictBase(root string, conflict Conflict, k *keys) ([]byte, error) {
+	fi, err := os.Stat(conflict.Copy)
+	if err != nil {
+		return nil, err
+	}
+	return baseVersion(root, conflict.Original, fi.ModTime(), k)
+}
+
+// ResolveConflict merges the conflict copy matching name into its original,
+// opens the result in the editor and moves the copy to the trash. Names
+// match the path of the copy relative to the notebook root, like List. The
+// merge is three-way when the original was committed before the copy was
+// written, and two-way otherwise. A copy whose original is gone simply
+// takes its place.
+func ResolveConflict(name string) (*Conflict, error) {
+	conf := GetConfig()
+	editor := strings.Fields(conf.Editor)
+	if len(editor) == 0 {
+		return nil, EditorNotSetError(true)
+	}
+	root, err := conf.Root()
+	if err != nil {
+		return nil, err
+	}
+	if filepath.IsAbs(name) {
+		if rel, ok := relPath(root, name); ok {
+			name = rel
+		}
+	}
+	name = filepath.ToSlash(name)
+	conflicts, err := Conflicts()
+	if err != nil {
+		return nil, err
+	}
+	var matching []Conflict
+	var copies []string
+	for _, conflict := range conflicts {
+		rel, _ := relPath(root, conflict.Copy)
+		if strings.Contains(filepath.ToSlash(rel), name) {
+			matching = append(matching, conflict)
+			copies = append(copies, conflict.Copy)
+		}
+	}
+	chosen, err := single(copies)
+	if err != nil {
+		return nil, err
+	}
+	var conflict Conflict
+	for _, c := range matching {
+		if c.Copy == chosen {
+			conflict = c
+		}
+	}
+	rel, _ := filepath.Rel(root, conflict.Original)
+	copyRel, _ := filepath.Rel(root, conflict.Copy)
+
+	if conflict.Orphan {
+		if err := os.Rename(conflict.Copy, conflict.Original); err != nil {
+			return nil, err
+		}
+		if conf.AutoCommit {
+			if err := commitFiles(root, []string{copyRel, rel}, "Move conflict copy "+copyRel+" to "+rel); err != nil {
+				log.Print(err)
+			}
+		}
+		return &conflict, nil
+	}
+
+	k, err := loadKeys()
+	if err != nil {
+		return nil, err
+	}
+	original, err := readNote(conflict.Original, k)
+	if err != nil {
+		return nil, err
+	}
+	copy, err := readNote(conflict.Copy, k)
+	if err != nil {
+		return nil, err
+	}
+	copyName := filepath.Base(conflict.Copy)
+	var merged []byte
+	// A base equal to one side means only the other side changed, and
+	// the merge takes it whole, deletions included. Without a base the
+	// two-way merge keeps every line either side has, so lines one side
+	// deleted come back and are left to remove in the editor.
+	base, err := conflictBase(root, conflict, k)
+	if err == nil {
+		merged, err = mergeThreeWay(original, base, copy, copyName)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		merged = mergeTwoWay(original, copy, copyName)
+	}
+	if err := writeNote(conflict.Original, merged, k); err != nil {
+		return nil, err
+	}
+	if IsEncrypted(conflict.Original) {
+		err = editEncrypted(editor, conflict.Original)
+	} else {
+		err = runEditor(editor, conflict.Original)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := trashFile(root, conflict.Copy); err != nil {
+		return nil, err
+	}
+	if conf.AutoCommit {
+		if err := commitFiles(root, []string{rel, copyRel}, "Merge conflict copy "+copyRel+" into "+rel); err != nil {
+			log.Print(err)
+		}
+	}
+	return &conflict, nil
+}
@@ -0,0 +1,151 @@
+package lib
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConflicts(t *testing.T) {
+	dir, _ := createTestFiles()
+	defer os.RemoveAll(dir)
+	SetConfig(&Config{NotesDir: dir, Editor: "true"})
+	defer SetConfig(nil)
+	for _, name := range []string{
+		"todo.md", "todo (1).md", "todo (conflicted copy 2024-01-02).md",
+		"plan.sync-conflict-20240102-123456-ABCDEFG.md", "Chapter (2).md",
+	} {
+		ioutil.WriteFile(path.Join(dir, name), []byte("a\n"), 0644)
+	}
+
+	// Numbered copies only count when the original exists.
+	conflicts, err := Conflicts()
+	assert.Nil(t, err)
+	kinds := make(map[string]Conflict)
+	for _, conflict := range conflicts {
+		kinds[conflict.Kind] = conflict
+	}
+	assert.Equal(t, 3, len(conflicts))
+	assert.Equal(t, path.Join(dir, "todo.md"), kinds["drive"].Original)
+	assert.Equal(t, path.Join(dir, "todo.md"), kinds["dropbox"].Original)
+	assert.False(t, kinds["dropbox"].Orphan)
+	assert.Equal(t, path.Join(dir, "plan.md"), kinds["syncthing"].Original)
+	assert.True(t, kinds["syncthing"].Orphan)
+
+	// Names match the path inside the notebook, not the notebook's own.
+	_, err = ResolveConflict(path.Base(dir))
+	assert.Equal(t, NoFilesError(true), err)
+
+	// An orphaned copy takes the place of its original.
+	_, err = ResolveConflict("sync-conflict")
+	assert.Nil(t, err)
+	_, err = os.Stat(path.Join(dir, "plan.md"))
+	assert.Nil(t, err)
+
+	_, err = ResolveConflict("todo")
+	_, ok := err.(*MultipleFilesError)
+	assert.True(t, ok)
+
+	// The copy picked among several is the one merged.
+	dropbox := path.Join(dir, "todo (conflicted copy 2024-01-02).md")
+	GetConfig().Choose = func(files []string) (string, error) {
+		return dropbox, nil
+	}
+	conflict, err := ResolveConflict("todo")
+	GetConfig().Choose = nil
+	assert.Nil(t, err)
+	assert.Equal(t, dropbox, conflict.Copy)
+	_, err = os.Stat(dropbox)
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(path.Join(dir, "todo (1).md"))
+	assert.Nil(t, err)
+
+	// Without history, lines added on either side are kept and lines
+	// changed on both sides are marked.
+	ioutil.WriteFile(path.Join(dir, "todo.md"), []byte("a\nb\nmine\n"), 0644)
+	ioutil.WriteFile(path.Join(dir, "todo (1).md"), []byte("a\ntheirs\nc\n"), 0644)
+	_, err = ResolveConflict("todo (1)")
+	assert.Nil(t, err)
+	data, _ := ioutil.ReadFile(path.Join(dir, "todo.md"))
+	assert.Equal(t, "a\n<<<<<<< original\nb\nmine\n=======\ntheirs\nc\n>>>>>>> todo (1).md\n", string(data))
+	_, err = os.Stat(path.Join(dir, "todo (1).md"))
+	assert.True(t, os.IsNotExist(err))
+	entries, _ := ListTrash()
+	assert.Equal(t, 2, len(entries))
+}
+
+func TestMergeTwoWay(t *testing.T) {
+	merged := mergeTwoWay([]byte("a\nb\nc\n"), []byte("a\nc\nd\n"), "copy")
+	assert.Equal(t, "a\nb\nc\nd\n", string(merged))
+	merged = mergeTwoWay([]byte("same\n"), []byte("same\n"), "copy")
+	assert.Equal(t, "same\n", string(merged))
+}
+
+func TestResolveConflictThreeWay(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir, _ := createTestFiles()
+	defer os.RemoveAll(dir)
+	SetConfig(&Config{NotesDir: dir, Editor: "true", AutoCommit: true})
+	defer SetConfig(nil)
+
+	file := path.Join(dir, "list.md")
+	ioutil.WriteFile(file, []byte("one\ntwo\nthree\n"), 0644)
+	assert.Nil(t, commitNote(file, "Edit %s"))
+
+	// Changes to different lines on both sides merge cleanly.
+	ioutil.WriteFile(file, []byte("one\ntwo\nthree\nfour\n"), 0644)
+	ioutil.WriteFile(path.Join(dir, "list (conflicted copy).md"), []byte("zero\none\ntwo\nthree\n"), 0644)
+	_, err := ResolveConflict("conflicted")
+	assert.Nil(t, err)
+	data, _ := ioutil.ReadFile(file)
+	assert.Equal(t, "zero\none\ntwo\nthree\nfour\n", string(data))
+	revisions, _ := Log("list.md")
+	assert.Equal(t, "Merge conflict copy list (conflicted copy).md into list.md", revisions[0].Subject)
+}
+
+func TestResolveConflictCommittedEdits(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir, _ := createTestFiles()
+	defer os.RemoveAll(dir)
+	SetConfig(&Config{NotesDir: dir, Editor: "true", AutoCommit: true})
+	defer SetConfig(nil)
+
+	// The common version was committed before the copy was written, and
+	// the local edit after it, like autocommit does.
+	file := path.Join(dir, "list.md")
+	ioutil.WriteFile(file, []byte("one\ntwo\nthree\n"), 0644)
+	os.Setenv("GIT_COMMITTER_DATE", time.Now().Add(-2*time.Hour).Format(time.RFC3339))
+	err := commitNote(file, "Edit %s")
+	os.Unsetenv("GIT_COMMITTER_DATE")
+	assert.Nil(t, err)
+	copy := path.Join(dir, "list (conflicted copy).md")
+	ioutil.WriteFile(copy, []byte("zero\none\ntwo\nthree\n"), 0644)
+	copyTime := time.Now().Add(-time.Hour)
+	os.Chtimes(copy, copyTime, copyTime)
+	ioutil.WriteFile(file, []byte("one\ntwo\nthree\nfour\n"), 0644)
+	assert.Nil(t, commitNote(file, "Edit %s"))
+
+	_, err = ResolveConflict("conflicted")
+	assert.Nil(t, err)
+	data, _ := ioutil.ReadFile(file)
+	assert.Equal(t, "zero\none\ntwo\nthree\nfour\n", string(data))
+
+	// When the original wasn't edited since it was committed, the copy
+	// is taken whole, lines it deleted included.
+	ioutil.WriteFile(copy, []byte("zero\none\nthree\nfive\n"), 0644)
+	copyTime = time.Now().Add(time.Minute)
+	os.Chtimes(copy, copyTime, copyTime)
+	_, err = ResolveConflict(path.Join(dir, "list (conflicted"))
+	assert.Nil(t, err)
+	data, _ = ioutil.ReadFile(file)
+	assert.Equal(t, "zero\none\nthree\nfive\n", string(data))
+}
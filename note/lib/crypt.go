@@ -0,0 +1,244 @@
+package lib
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"filippo.io/age"
+)
+
+// Notes ending in one of these extensions are stored encrypted with age.
+// They are decrypted with the identities of IdentityFile or, for notes
+// encrypted with a passphrase, the passphrase kept in PassphraseFile.
+var encryptedExts = []string{".age", ".enc"}
+
+type NoKeyError bool
+
+func (e NoKeyError) Error() string {
+	return "No identity or passphrase file available to decrypt or encrypt notes."
+}
+
+func IsEncrypted(file string) bool {
+	ext := filepath.Ext(file)
+	for _, encryptedExt := range encryptedExts {
+		if ext == encryptedExt {
+			return true
+		}
+	}
+	return false
+}
+
+type keys struct {
+	identities []age.Identity
+	recipients []age.Recipient
+}
+
+// loadKeys reads the configured identity and passphrase files. Missing
+// files are not an error, they just provide no keys.
+func loadKeys() (*keys, error) {
+	conf := GetConfig()
+	k := &keys{}
+	if conf.IdentityFile != "" {
+		f, err := os.Open(conf.IdentityFile)
+		if err == nil {
+			identities, err := age.ParseIdentities(f)
+			f.Close()
+			if err != nil {
+				return nil, err
+			}
+			for _, identity := range identities {
+				k.identities = append(k.identities, identity)
+				if x25519, ok := identity.(*age.X25519Identity); ok {
+					k.recipients = append(k.recipients, x25519.Recipient())
+				}
+			}
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	if conf.PassphraseFile != "" {
+		data, err := ioutil.ReadFile(conf.PassphraseFile)
+		if err == nil {
+			passphrase := strings.TrimRight(string(data), "\r\n")
+			identity, err := age.NewScryptIdentity(passphrase)
+			if err != nil {
+				return nil, err
+			}
+			k.identities = append(k.identities, identity)
+			// A passphrase can't be combined with other recipients.
+			if len(k.recipients) == 0 {
+				recipient, err := age.NewScryptRecipient(passphrase)
+				if err != nil {
+					return nil, err
+				}
+				k.recipients = append(k.recipients, recipient)
+			}
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	return k, nil
+}
+
+func (k *keys) decrypt(file string) ([]byte, error) {
+	if len(k.identities) == 0 {
+		return nil, NoKeyError(true)
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	// An empty file is a note created but never saved.
+	if fi, err := f.Stat(); err == nil && fi.Size() == 0 {
+		return nil, nil
+	}
+	r, err := age.Decrypt(f, k.identities...)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(r)
+}
+
+func (k *keys) encrypt(file string, data []byte) error {
+	if len(k.recipients) == 0 {
+		return NoKeyError(true)
+	}
+	var buf bytes.Buffer
+	w, err := age.Encrypt(&buf, k.recipients...)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return writeFileAtomic(file, buf.Bytes())
+}
+
+// openNote returns a reader over the plain text of a note, decrypting it
+// when needed.
+func openNote(file string, k *keys) (io.ReadCloser, error) {
+	if !IsEncrypted(file) {
+		return os.Open(file)
+	}
+	data, err := k.decrypt(file)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+// editEncrypted decrypts file into a private temporary file, runs the
+// editor on it and encrypts the result back into file.
+func editEncrypted(editor []string, file string) error {
+	k, err := loadKeys()
+	if err != nil {
+		return err
+	}
+	data, err := k.decrypt(file)
+	if err != nil {
+		return err
+	}
+	dir, err := ioutil.TempDir("", "note")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	plain := filepath.Join(dir, strings.TrimSuffix(filepath.Base(file), filepath.Ext(file)))
+	if err := ioutil.WriteFile(plain, data, 0600); err != nil {
+		return err
+	}
+	if err := runEditor(editor, plain); err != nil {
+		return err
+	}
+	edited, err := ioutil.ReadFile(plain)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(data, edited) {
+		if fi, err := os.Stat(file); err == nil && fi.Size() > 0 {
+			return nil
+		}
+	}
+	return k.encrypt(file, edited)
+}
+
+// encryptNote turns a plain text note into an encrypted one and returns
+// the name of the encrypted note.
+func encryptNote(file string) (string, error) {
+	k, err := loadKeys()
+	if err != nil {
+		return "", err
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	encrypted := file + encryptedExts[0]
+	if err := k.encrypt(encrypted, data); err != nil {
+		return "", err
+	}
+	return encrypted, os.Remove(file)
+}
+
+// encryptCommitted encrypts a plain text note like encryptNote and commits
+// the removal of the plain note together with the encrypted one. Encrypting
+// doesn't rewrite history, so a warning tells when the plain text was
+// committed before.
+func encryptCommitted(plain string) (string, error) {
+	root, err := rootOf(plain)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(root, plain)
+	if err != nil {
+		return "", err
+	}
+	if isTracked(root, rel) {
+		log.Print("note: '" + filepath.ToSlash(rel) + "' was committed before, its plain text stays in the git history")
+	}
+	file, err := encryptNote(plain)
+	if err != nil {
+		return "", err
+	}
+	if GetConfig().AutoCommit {
+		if err := commitFiles(root, []string{rel, rel + encryptedExts[0]}, "Encrypt "+filepath.ToSlash(rel)); err != nil {
+			log.Print(err)
+		}
+	}
+	return file, nil
+}
+
+// GenerateIdentity writes a new age identity to the configured identity
+// file, which must not exist yet, and returns its public key.
+func GenerateIdentity() (string, error) {
+	file := GetConfig().IdentityFile
+	if file == "" {
+		return "", NoKeyError(true)
+	}
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return "", err
+	}
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return "", err
+	}
+	recipient := identity.Recipient().String()
+	_, err = f.WriteString("# public key: " + recipient + "\n" + identity.String() + "\n")
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return recipient, err
+}
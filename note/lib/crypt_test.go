@@ -0,0 +1,90 @@
+package lib
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncryptedNotes(t *testing.T) {
+	dir, _ := createTestFiles()
+	defer os.RemoveAll(dir)
+	keyDir, _ := ioutil.TempDir("", "notekeys")
+	defer os.RemoveAll(keyDir)
+	editor := path.Join(keyDir, "editor")
+	ioutil.WriteFile(editor, []byte("#!/bin/sh\necho secret >> \"$1\"\n"), 0755)
+	SetConfig(&Config{NotesDir: dir, Editor: editor, IdentityFile: path.Join(keyDir, "identity")})
+	defer SetConfig(nil)
+
+	// Encrypting without keys fails.
+	err := EditWith("vault", EditOptions{Create: true, Encrypt: true})
+	assert.Equal(t, NoKeyError(true), err)
+	os.Remove(path.Join(dir, "vault.age"))
+
+	_, err = GenerateIdentity()
+	assert.Nil(t, err)
+	_, err = GenerateIdentity()
+	assert.NotNil(t, err)
+
+	err = EditWith("vault", EditOptions{Create: true, Encrypt: true})
+	assert.Nil(t, err)
+	data, _ := ioutil.ReadFile(path.Join(dir, "vault.age"))
+	assert.False(t, bytes.Contains(data, []byte("secret")))
+
+	// Editing again decrypts and re-encrypts the note.
+	err = Edit("vault", false)
+	assert.Nil(t, err)
+	k, _ := loadKeys()
+	plain, err := k.decrypt(path.Join(dir, "vault.age"))
+	assert.Nil(t, err)
+	assert.Equal(t, "secret\nsecret\n", string(plain))
+
+	// Plain text notes are converted.
+	ioutil.WriteFile(path.Join(dir, "plain"), []byte("password\n"), 0644)
+	err = EditWith("plain", EditOptions{Encrypt: true})
+	assert.Nil(t, err)
+	_, err = os.Stat(path.Join(dir, "plain"))
+	assert.True(t, os.IsNotExist(err))
+
+	// Encrypted notes are searched only when they can be decrypted.
+	_, err = RebuildIndex()
+	assert.Nil(t, err)
+	matches, err := Grep("secret", GrepOptions{})
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{path.Join(dir, "vault.age"), path.Join(dir, "plain.age")}, MatchedFiles(matches))
+	GetConfig().IdentityFile = ""
+	matches, err = Grep("secret", GrepOptions{})
+	assert.Nil(t, err)
+	assert.Nil(t, matches)
+}
+
+func TestEncryptCommitted(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir, _ := ioutil.TempDir("", "note")
+	defer os.RemoveAll(dir)
+	keyDir, _ := ioutil.TempDir("", "notekeys")
+	defer os.RemoveAll(keyDir)
+	SetConfig(&Config{NotesDir: dir, Editor: "true", AutoCommit: true, IdentityFile: path.Join(keyDir, "identity")})
+	defer SetConfig(nil)
+	_, err := GenerateIdentity()
+	assert.Nil(t, err)
+
+	// The removal of the plain note is committed with the encrypted one.
+	file := path.Join(dir, "secret.md")
+	ioutil.WriteFile(file, []byte("password=hunter2\n"), 0644)
+	assert.Nil(t, commitNote(file, "Edit %s"))
+	assert.Nil(t, EditWith("secret.md", EditOptions{Encrypt: true}))
+	tracked, err := git(dir, "ls-files")
+	assert.Nil(t, err)
+	assert.Equal(t, "secret.md.age\n", tracked)
+	changed, err := git(dir, "show", "--format=%s", "--name-status", "HEAD")
+	assert.Nil(t, err)
+	assert.Equal(t, "Encrypt secret.md\n\nD\tsecret.md\nA\tsecret.md.age\n", changed)
+}
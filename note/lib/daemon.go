@@ -0,0 +1,93 @@
+package lib
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"syscall"
+	"time"
+)
+
+const (
+	// dialTimeout bounds how long connecting to the watch daemon may take
+	// before the notebook is read instead.
+	dialTimeout  = 100 * time.Millisecond
+	queryTimeout = 10 * time.Second
+)
+
+// daemonRequest is a query sent to the watch daemon: "files", "tags",
+// "links", "candidates" of Pattern or "status". Each connection carries
+// one query and its response, as JSON. The candidates are answered with
+// the notes that can't contain the pattern, so notes the daemon doesn't
+// know of yet are still searched.
+type daemonRequest struct {
+	Op      string `json:"op"`
+	Pattern string `json:"pattern,omitempty"`
+}
+
+type daemonResponse struct {
+	Error  string              `json:"error,omitempty"`
+	Files  []string            `json:"files,omitempty"`
+	Skip   []string            `json:"skip,omitempty"`
+	Tags   map[string][]string `json:"tags,omitempty"`
+	Links  map[string][]Link   `json:"links,omitempty"`
+	Status *WatchStatus        `json:"status,omitempty"`
+}
+
+func queryDaemon(root string, req daemonRequest) (*daemonResponse, error) {
+	conn, err := net.DialTimeout("unix", socketPath(root), dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(queryTimeout))
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, err
+	}
+	var resp daemonResponse
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, errors.New(resp.Error)
+	}
+	return &resp, nil
+}
+
+// fromDaemon asks the watch daemon of the selected notebook, when the
+// config allows it and one is running. It returns nil otherwise, or when
+// the query fails, and the caller reads the notebook itself.
+func fromDaemon(req daemonRequest) *daemonResponse {
+	conf := GetConfig()
+	if !conf.UseDaemon {
+		return nil
+	}
+	root, err := conf.Root()
+	if err != nil {
+		return nil
+	}
+	resp, err := queryDaemon(root, req)
+	if err != nil {
+		// Not having a daemon running is the usual case.
+		if !errors.Is(err, syscall.ENOENT) && !errors.Is(err, syscall.ECONNREFUSED) {
+			log.Print(err)
+		}
+		return nil
+	}
+	return resp
+}
+
+// Watching returns the status of the watch daemon of the selected notebook,
+// or nil when none is running.
+func Watching() (*WatchStatus, error) {
+	root, err := GetConfig().Root()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := queryDaemon(root, daemonRequest{Op: "status"})
+	if err != nil {
+		return nil, nil
+	}
+	return resp.Status, nil
+}
@@ -0,0 +1,91 @@
+package lib
+
+// maxDiffCells bounds the size of the table used to diff two texts. Texts
+// whose differing middle parts are larger are treated as entirely changed.
+const maxDiffCells = 4 * 1024 * 1024
+
+// hunk is a run of lines either common to both texts or differing between
+// them, in which case a holds the lines of the first text and b the lines
+// of the second one.
+type hunk struct {
+	equal bool
+	a     []string
+	b     []string
+}
+
+// diffLines splits two texts, given as lines, into common and differing
+// hunks using their longest common subsequence.
+func diffLines(a, b []string) []hunk {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	var hunks []hunk
+	if prefix > 0 {
+		hunks = append(hunks, hunk{equal: true, a: a[:prefix], b: b[:prefix]})
+	}
+	hunks = append(hunks, diffMiddle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	if suffix > 0 {
+		hunks = append(hunks, hunk{equal: true, a: a[len(a)-suffix:], b: b[len(b)-suffix:]})
+	}
+	return hunks
+}
+
+func diffMiddle(a, b []string) []hunk {
+	if len(a) == 0 && len(b) == 0 {
+		return nil
+	}
+	if len(a) == 0 || len(b) == 0 || (len(a)+1)*(len(b)+1) > maxDiffCells {
+		return []hunk{{a: a, b: b}}
+	}
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	var hunks []hunk
+	add := func(equal bool, aLine, bLine *string) {
+		if len(hunks) == 0 || hunks[len(hunks)-1].equal != equal {
+			hunks = append(hunks, hunk{equal: equal})
+		}
+		h := &hunks[len(hunks)-1]
+		if aLine != nil {
+			h.a = append(h.a, *aLine)
+		}
+		if bLine != nil {
+			h.b = append(h.b, *bLine)
+		}
+	}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			add(true, &a[i], &b[j])
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			add(false, &a[i], nil)
+			i++
+		default:
+			add(false, nil, &b[j])
+			j++
+		}
+	}
+	return hunks
+}
@@ -0,0 +1,596 @@
+package lib
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/yuin/goldmark"
+	highlighting "github.com/yuin/goldmark-highlighting/v2"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+type ExportDirError string
+
+func (e ExportDirError) Error() string {
+	return "Can't export into '" + string(e) + "', it is inside the notes directory."
+}
+
+//go:embed site
+var siteFiles embed.FS
+
+var pageTemplate = template.Must(template.ParseFS(siteFiles, "site/page.html"))
+
+// Files of the site that aren't pages. The leading underscore keeps them
+// apart from the folders of the notebook.
+const (
+	siteStaticDir   = "_static"
+	siteTagsDir     = "_tags"
+	siteSearchIndex = "_search.js"
+	// searchTextLimit bounds the text of a note kept in the search index.
+	searchTextLimit = 16 * 1024
+)
+
+// markdownExts are the extensions of notes exported as pages. Other files
+// are attachments, copied as they are.
+var markdownExts = map[string]bool{
+	"":          true,
+	".md":       true,
+	".markdown": true,
+	".mdown":    true,
+	".txt":      true,
+}
+
+// ExportResult tells what ExportHTML wrote. Encrypted notes are never
+// published and are listed in Skipped.
+type ExportResult struct {
+	Notes       int
+	Attachments int
+	Skipped     []string
+}
+
+// siteLink is an entry of the navigation or the listings of a page. Count
+// is the number of notes of a folder or tag.
+type siteLink struct {
+	Name  string
+	Href  string
+	Count int
+}
+
+type sitePage struct {
+	Site      string
+	Title     string
+	ShowTitle bool
+	Root      string
+	Crumbs    []siteLink
+	Tags      []siteLink
+	Content   template.HTML
+	Folders   []siteLink
+	Notes     []siteLink
+}
+
+type searchEntry struct {
+	Title string   `json:"title"`
+	URL   string   `json:"url"`
+	Tags  []string `json:"tags"`
+	Text  string   `json:"text"`
+}
+
+type exportNote struct {
+	file  string
+	page  string
+	meta  *Meta
+	body  []byte
+	title string
+}
+
+// exporter holds the state of one export. Page names and hrefs are slash
+// separated and relative to the site root.
+type exporter struct {
+	root    string
+	out     string
+	files   []string
+	notes   map[string]*exportNote
+	skipped map[string]bool
+}
+
+var exportKey = parser.NewContextKey()
+
+var siteMarkdown = goldmark.New(
+	goldmark.WithExtensions(
+		extension.GFM,
+		highlighting.NewHighlighting(highlighting.WithStyle("github")),
+	),
+	goldmark.WithParserOptions(
+		parser.WithAutoHeadingID(),
+		parser.WithASTTransformers(util.Prioritized(linkTransformer{}, 100)),
+	),
+)
+
+func isMarkdown(file string) bool {
+	return markdownExts[strings.ToLower(filepath.Ext(file))]
+}
+
+// pageName returns the page a note is exported to.
+func pageName(rel string) string {
+	return filepath.ToSlash(withoutExt(rel)) + ".html"
+}
+
+// rootPrefix returns the relative URL from page back to the site root.
+func rootPrefix(page string) string {
+	return strings.Repeat("../", strings.Count(page, "/"))
+}
+
+// relHref returns the relative URL of target seen from the page from.
+func relHref(from, target string) string {
+	rel, err := filepath.Rel(filepath.FromSlash(path.Dir(from)), filepath.FromSlash(target))
+	if err != nil {
+		rel = rootPrefix(from) + target
+	}
+	return (&url.URL{Path: filepath.ToSlash(rel)}).EscapedPath()
+}
+
+// headingID returns the id goldmark gives a heading, for the anchors of
+// wiki links.
+func headingID(heading string) string {
+	var id []rune
+	for _, r := range strings.ToLower(strings.TrimSpace(heading)) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_':
+			id = append(id, r)
+		case unicode.IsSpace(r):
+			id = append(id, '-')
+		}
+	}
+	return string(id)
+}
+
+// firstHeading returns the title of a note starting with one.
+func firstHeading(body []byte) string {
+	line := bytes.TrimLeft(body, "\n")
+	if !bytes.HasPrefix(line, []byte("# ")) {
+		return ""
+	}
+	if i := bytes.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+	return strings.TrimSpace(string(line[2:]))
+}
+
+// tagPage returns the page listing the notes of a tag.
+func tagPage(tag string) string {
+	name := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, tag)
+	return siteTagsDir + "/" + name + ".html"
+}
+
+// wikiToMarkdown turns the wiki links of a note into markdown links with a
+// "wiki:" destination, resolved when the note is rendered. Code blocks and
+// code spans are left alone.
+func wikiToMarkdown(body []byte) []byte {
+	lines := strings.Split(string(body), "\n")
+	inCode := false
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCode = !inCode
+			continue
+		}
+		if inCode || !strings.Contains(line, "[[") {
+			continue
+		}
+		spans := strings.Split(line, "`")
+		for j := 0; j < len(spans); j += 2 {
+			spans[j] = wikiLinkRegex.ReplaceAllStringFunc(spans[j], func(link string) string {
+				m := wikiLinkRegex.FindStringSubmatch(link)
+				target := strings.TrimSpace(m[1])
+				label := target
+				if m[3] != "" {
+					label = strings.TrimSpace(m[3][1:])
+				}
+				dest := "wiki:" + url.PathEscape(target)
+				if m[2] != "" {
+					dest += "#" + headingID(m[2][1:])
+				}
+				return "[" + label + "](" + dest + ")"
+			})
+		}
+		lines[i] = strings.Join(spans, "`")
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
+
+// href returns the URL of the page or attachment file is exported to, seen
+// from the page from, and false when file isn't exported.
+func (e *exporter) href(from, file string) (string, bool) {
+	if file == "" || e.skipped[file] {
+		return "", false
+	}
+	if note, ok := e.notes[file]; ok {
+		return relHref(from, note.page), true
+	}
+	rel, ok := relPath(e.root, file)
+	if !ok {
+		return "", false
+	}
+	return relHref(from, filepath.ToSlash(rel)), true
+}
+
+type exportContext struct {
+	e    *exporter
+	note *exportNote
+}
+
+// linkTransformer points the links of a note at the exported pages.
+type linkTransformer struct{}
+
+func (linkTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	ctx, ok := pc.Get(exportKey).(*exportContext)
+	if !ok {
+		return
+	}
+	var broken []*ast.Link
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		var dest *[]byte
+		switch link := n.(type) {
+		case *ast.Link:
+			dest = &link.Destination
+		case *ast.Image:
+			dest = &link.Destination
+		default:
+			return ast.WalkContinue, nil
+		}
+		target, fragment := string(*dest), ""
+		if i := strings.Index(target, "#"); i >= 0 {
+			target, fragment = target[:i], target[i:]
+		}
+		kind := MarkdownLink
+		if strings.HasPrefix(target, "wiki:") {
+			kind, target = WikiLink, strings.TrimPrefix(target, "wiki:")
+		} else if target == "" || externalLinkRegex.MatchString(target) || strings.HasPrefix(target, "/") {
+			return ast.WalkContinue, nil
+		}
+		if unescaped, err := url.PathUnescape(target); err == nil {
+			target = unescaped
+		}
+		file := resolveLink(Link{Kind: kind, Target: target}, ctx.note.file, ctx.e.root, ctx.e.files)
+		if href, ok := ctx.e.href(ctx.note.page, file); ok {
+			*dest = []byte(href + fragment)
+		} else if link, ok := n.(*ast.Link); ok && kind == WikiLink {
+			broken = append(broken, link)
+		}
+		return ast.WalkContinue, nil
+	})
+	// Wiki links to missing notes are kept as plain text.
+	for _, link := range broken {
+		parent := link.Parent()
+		for child := link.FirstChild(); child != nil; child = link.FirstChild() {
+			parent.InsertBefore(parent, link, child)
+		}
+		parent.RemoveChild(parent, link)
+	}
+}
+
+func (e *exporter) render(note *exportNote) (template.HTML, []byte, error) {
+	src := wikiToMarkdown(note.body)
+	pc := parser.NewContext()
+	pc.Set(exportKey, &exportContext{e, note})
+	doc := siteMarkdown.Parser().Parse(text.NewReader(src), parser.WithContext(pc))
+	var buf bytes.Buffer
+	if err := siteMarkdown.Renderer().Render(&buf, src, doc); err != nil {
+		return "", nil, err
+	}
+	// The plain text of the note, for the search index.
+	var plain bytes.Buffer
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if plain.Len() >= searchTextLimit {
+			return ast.WalkStop, nil
+		}
+		if n.Type() == ast.TypeBlock {
+			if !entering {
+				plain.WriteByte(' ')
+			} else if n.Kind() == ast.KindCodeBlock || n.Kind() == ast.KindFencedCodeBlock {
+				lines := n.Lines()
+				for i := 0; i < lines.Len(); i++ {
+					segment := lines.At(i)
+					plain.Write(segment.Value(src))
+				}
+			}
+		} else if t, ok := n.(*ast.Text); ok && entering {
+			plain.Write(t.Segment.Value(src))
+			if t.SoftLineBreak() || t.HardLineBreak() {
+				plain.WriteByte(' ')
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	plainText := plain.Bytes()
+	if len(plainText) > searchTextLimit {
+		plainText = plainText[:searchTextLimit]
+	}
+	return template.HTML(buf.String()), bytes.Join(bytes.Fields(plainText), []byte(" ")), nil
+}
+
+func (e *exporter) writePage(name string, page *sitePage) error {
+	page.Root = rootPrefix(name)
+	page.Site = filepath.Base(e.root)
+	var buf bytes.Buffer
+	if err := pageTemplate.Execute(&buf, page); err != nil {
+		return err
+	}
+	return e.writeFile(name, buf.Bytes())
+}
+
+func (e *exporter) writeFile(name string, data []byte) error {
+	file := filepath.Join(e.out, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, data, 0644)
+}
+
+func (e *exporter) copyFile(file, rel string) error {
+	target := filepath.Join(e.out, rel)
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	src, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// crumbs returns the folders leading to the page name.
+func crumbs(name string) []siteLink {
+	dir := path.Dir(name)
+	if dir == "." {
+		return nil
+	}
+	var links []siteLink
+	parts := strings.Split(dir, "/")
+	for i := range parts {
+		folder := strings.Join(parts[:i+1], "/")
+		links = append(links, siteLink{Name: parts[i], Href: relHref(name, folder+"/index.html")})
+	}
+	return links
+}
+
+// ExportHTML writes the selected notebook to out as a static site: a page
+// per markdown note with its links pointing at the other pages, an index
+// page per folder and per tag, the attachments and a search index used by
+// the pages. A note named index becomes the page of its folder, above the
+// listing of the folder. Encrypted notes are left out.
+func ExportHTML(out string) (*ExportResult, error) {
+	root, err := GetConfig().Root()
+	if err != nil {
+		return nil, err
+	}
+	absOut, err := filepath.Abs(out)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := relPath(root, absOut); ok {
+		return nil, ExportDirError(out)
+	}
+	files, err := List("")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	k, err := loadKeys()
+	if err != nil {
+		return nil, err
+	}
+	e := &exporter{root, absOut, files, make(map[string]*exportNote), make(map[string]bool)}
+	result := &ExportResult{}
+	var notes []*exportNote
+	var attachments []string
+	for _, file := range files {
+		rel, _ := relPath(root, file)
+		switch {
+		case IsEncrypted(file):
+			e.skipped[file] = true
+			result.Skipped = append(result.Skipped, file)
+		case isMarkdown(file):
+			data, err := readNote(file, k)
+			if err != nil {
+				return nil, err
+			}
+			meta, body := SplitFrontMatter(data)
+			title := meta.Title
+			if title == "" {
+				title = firstHeading(body)
+			}
+			if title == "" {
+				title = withoutExt(filepath.Base(file))
+				if title == "index" {
+					title = filepath.Base(filepath.Dir(file))
+				}
+			}
+			note := &exportNote{file, pageName(rel), meta, body, title}
+			e.notes[file] = note
+			notes = append(notes, note)
+		default:
+			attachments = append(attachments, file)
+		}
+	}
+
+	// Folders and tags, with the number of notes below them.
+	folderNotes := map[string][]*exportNote{".": nil}
+	subfolders := make(map[string]map[string]bool)
+	totals := make(map[string]int)
+	tagNotes := make(map[string][]*exportNote)
+	for _, note := range notes {
+		dir := path.Dir(note.page)
+		folderNotes[dir] = append(folderNotes[dir], note)
+		for d := dir; ; d = path.Dir(d) {
+			totals[d]++
+			if d == "." {
+				break
+			}
+			parent := path.Dir(d)
+			if subfolders[parent] == nil {
+				subfolders[parent] = make(map[string]bool)
+			}
+			subfolders[parent][d] = true
+			if _, ok := folderNotes[d]; !ok {
+				folderNotes[d] = nil
+			}
+		}
+		for _, tag := range note.meta.Tags {
+			tagNotes[tag] = append(tagNotes[tag], note)
+		}
+	}
+
+	var index []searchEntry
+	folderIndex := make(map[string]*exportNote)
+	for _, note := range notes {
+		content, plain, err := e.render(note)
+		if err != nil {
+			return nil, err
+		}
+		tags := note.meta.Tags
+		if tags == nil {
+			tags = []string{}
+		}
+		index = append(index, searchEntry{note.title, note.page, tags, string(plain)})
+		page := &sitePage{
+			Title:     note.title,
+			ShowTitle: firstHeading(note.body) == "",
+			Crumbs:    crumbs(note.page),
+			Content:   content,
+		}
+		for _, tag := range note.meta.Tags {
+			page.Tags = append(page.Tags, siteLink{Name: tag, Href: relHref(note.page, tagPage(tag))})
+		}
+		if path.Base(note.page) == "index.html" {
+			// Written with the listing of its folder below.
+			folderIndex[path.Dir(note.page)] = note
+			if err := e.writeFolder(path.Dir(note.page), page, folderNotes, subfolders, totals); err != nil {
+				return nil, err
+			}
+		} else if err := e.writePage(note.page, page); err != nil {
+			return nil, err
+		}
+		result.Notes++
+	}
+	for dir := range folderNotes {
+		if folderIndex[dir] != nil {
+			continue
+		}
+		title := path.Base(dir)
+		if dir == "." {
+			title = filepath.Base(root)
+		}
+		page := &sitePage{Title: title, ShowTitle: true}
+		if err := e.writeFolder(dir, page, folderNotes, subfolders, totals); err != nil {
+			return nil, err
+		}
+	}
+
+	var tags []string
+	for tag := range tagNotes {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	tagsIndex := siteTagsDir + "/index.html"
+	tagsPage := &sitePage{Title: "Tags", ShowTitle: true}
+	for _, tag := range tags {
+		tagsPage.Notes = append(tagsPage.Notes, siteLink{"#" + tag, relHref(tagsIndex, tagPage(tag)), len(tagNotes[tag])})
+		page := &sitePage{Title: "#" + tag, ShowTitle: true}
+		for _, note := range tagNotes[tag] {
+			page.Notes = append(page.Notes, siteLink{Name: note.title, Href: relHref(tagPage(tag), note.page)})
+		}
+		if err := e.writePage(tagPage(tag), page); err != nil {
+			return nil, err
+		}
+	}
+	if err := e.writePage(tagsIndex, tagsPage); err != nil {
+		return nil, err
+	}
+
+	if index == nil {
+		index = []searchEntry{}
+	}
+	data, err := json.Marshal(index)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.writeFile(siteSearchIndex, append(append([]byte("var searchIndex = "), data...), ";\n"...)); err != nil {
+		return nil, err
+	}
+	for _, asset := range []string{"style.css", "search.js"} {
+		data, err := siteFiles.ReadFile("site/" + asset)
+		if err != nil {
+			return nil, err
+		}
+		if err := e.writeFile(siteStaticDir+"/"+asset, data); err != nil {
+			return nil, err
+		}
+	}
+	for _, file := range attachments {
+		rel, _ := relPath(root, file)
+		if err := e.copyFile(file, rel); err != nil {
+			return nil, err
+		}
+		result.Attachments++
+	}
+	return result, nil
+}
+
+// writeFolder writes the index page of dir, listing its subfolders and
+// notes below the content page already has.
+func (e *exporter) writeFolder(dir string, page *sitePage, folderNotes map[string][]*exportNote, subfolders map[string]map[string]bool, totals map[string]int) error {
+	name := "index.html"
+	if dir != "." {
+		name = dir + "/index.html"
+	}
+	if page.Crumbs == nil {
+		page.Crumbs = crumbs(name)
+	}
+	var dirs []string
+	for sub := range subfolders[dir] {
+		dirs = append(dirs, sub)
+	}
+	sort.Strings(dirs)
+	for _, sub := range dirs {
+		page.Folders = append(page.Folders, siteLink{path.Base(sub), relHref(name, sub+"/index.html"), totals[sub]})
+	}
+	for _, note := range folderNotes[dir] {
+		if note.page == name {
+			continue
+		}
+		page.Notes = append(page.Notes, siteLink{Name: note.title, Href: relHref(name, note.page)})
+	}
+	return e.writePage(name, page)
+}
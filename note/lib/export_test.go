@@ -0,0 +1,77 @@
+package lib
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportHTML(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "note")
+	defer os.RemoveAll(dir)
+	out, _ := ioutil.TempDir("", "site")
+	defer os.RemoveAll(out)
+	SetConfig(&Config{NotesDir: dir})
+	defer SetConfig(nil)
+	notes := map[string]string{
+		"runbooks/deploy.md":  "---\ntitle: Deploy\ntags: [ops]\n---\nSee [[restart#Rolling restart|restarts]], [[missing]] and ![graph](../img/graph.png).\n\n`[[code]]`\n",
+		"runbooks/restart.md": "# Restart\n\n## Rolling restart\n\nBack to [deploy](deploy.md).\n",
+		"index.md":            "Welcome.\n",
+		"img/graph.png":       "png",
+		"secret.md.age":       "encrypted",
+	}
+	for name, content := range notes {
+		os.MkdirAll(path.Dir(path.Join(dir, name)), 0755)
+		ioutil.WriteFile(path.Join(dir, name), []byte(content), 0644)
+	}
+
+	result, err := ExportHTML(out)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, result.Notes)
+	assert.Equal(t, 1, result.Attachments)
+	assert.Equal(t, []string{path.Join(dir, "secret.md.age")}, result.Skipped)
+	read := func(name string) string {
+		data, err := ioutil.ReadFile(path.Join(out, name))
+		assert.Nil(t, err)
+		return string(data)
+	}
+
+	// Links point at the exported pages and attachments.
+	deploy := read("runbooks/deploy.html")
+	assert.Contains(t, deploy, `<a href="restart.html#rolling-restart">restarts</a>`)
+	assert.Contains(t, deploy, `src="../img/graph.png"`)
+	assert.Contains(t, deploy, `<code>[[code]]</code>`)
+	assert.Contains(t, deploy, "missing")
+	assert.NotContains(t, deploy, "wiki:")
+	assert.Contains(t, deploy, `<h1>Deploy</h1>`)
+	assert.Contains(t, deploy, `href="../_tags/ops.html"`)
+	assert.Contains(t, deploy, `href="../_static/style.css"`)
+	restart := read("runbooks/restart.html")
+	assert.Contains(t, restart, `<a href="deploy.html">deploy</a>`)
+	assert.Contains(t, restart, `id="rolling-restart"`)
+	assert.Equal(t, "png", read("img/graph.png"))
+	_, err = os.Stat(path.Join(out, "secret.md.html"))
+	assert.True(t, os.IsNotExist(err))
+
+	// Folders and tags have index pages, and the index note is the page of
+	// its folder.
+	index := read("index.html")
+	assert.Contains(t, index, "Welcome.")
+	assert.Contains(t, index, `<a href="runbooks/index.html">runbooks/</a> <span class="count">2</span>`)
+	assert.Contains(t, read("runbooks/index.html"), `<a href="deploy.html">Deploy</a>`)
+	assert.Contains(t, read("_tags/ops.html"), `<a href="../runbooks/deploy.html">Deploy</a>`)
+	assert.Contains(t, read("_tags/index.html"), `<a href="ops.html">#ops</a>`)
+
+	search := read("_search.js")
+	assert.True(t, strings.HasPrefix(search, "var searchIndex = ["))
+	assert.Contains(t, search, `{"title":"Restart","url":"runbooks/restart.html","tags":[],"text":"Restart Rolling restart Back to deploy."}`)
+	read("_static/search.js")
+
+	// The site can't be written into the notes.
+	_, err = ExportHTML(path.Join(dir, "site"))
+	assert.Equal(t, ExportDirError(path.Join(dir, "site")), err)
+}
@@ -0,0 +1,81 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Mkdir creates the folder name, and any missing parents, in the selected
+// notebook and returns its path. Git doesn't track empty folders, so
+// nothing is committed until a note is created in it.
+func Mkdir(name string) (string, error) {
+	root, err := GetConfig().Root()
+	if err != nil {
+		return "", err
+	}
+	dir, err := notePath(root, name)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// TreeNode is a folder of a notebook. Name is its path relative to the
+// notebook root, "." for the root itself. Notes counts the notes directly
+// in the folder and Total those in its subfolders as well.
+type TreeNode struct {
+	Path     string
+	Name     string
+	Notes    int
+	Total    int
+	Files    []string
+	Children []*TreeNode
+}
+
+// Tree returns the folders of the selected notebook, with their notes and
+// subfolders sorted by name.
+func Tree() (*TreeNode, error) {
+	conf := GetConfig()
+	root, err := conf.Root()
+	if err != nil {
+		return nil, err
+	}
+	top := &TreeNode{Path: root, Name: "."}
+	nodes := map[string]*TreeNode{root: top}
+	walkNotes(root, conf.Ignore, func(path string, info os.FileInfo) {
+		parent := nodes[filepath.Dir(path)]
+		if parent == nil {
+			return
+		}
+		if !info.IsDir() {
+			parent.Files = append(parent.Files, path)
+			parent.Notes++
+			return
+		}
+		rel, _ := filepath.Rel(root, path)
+		node := &TreeNode{Path: path, Name: filepath.ToSlash(rel)}
+		nodes[path] = node
+		parent.Children = append(parent.Children, node)
+	})
+	top.count()
+	return top, nil
+}
+
+func (n *TreeNode) count() int {
+	n.Total = n.Notes
+	for _, child := range n.Children {
+		n.Total += child.count()
+	}
+	return n.Total
+}
+
+// Walk calls fn for the folder and every folder below it, parents first.
+func (n *TreeNode) Walk(fn func(*TreeNode)) {
+	fn(n)
+	for _, child := range n.Children {
+		child.Walk(fn)
+	}
+}
@@ -0,0 +1,46 @@
+package lib
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTree(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "note")
+	defer os.RemoveAll(dir)
+	SetConfig(&Config{NotesDir: dir, Ignore: []string{"*.swp"}})
+	defer SetConfig(nil)
+
+	// Folders are created with their parents.
+	created, err := Mkdir("work/2026")
+	assert.Nil(t, err)
+	assert.Equal(t, path.Join(dir, "work", "2026"), created)
+	_, err = Mkdir("empty")
+	assert.Nil(t, err)
+	_, err = Mkdir("..")
+	assert.Equal(t, InvalidNameError(".."), err)
+
+	for _, name := range []string{"a.md", "work/b.md", "work/2026/c.md", "work/2026/d.md", "work/e.swp"} {
+		ioutil.WriteFile(path.Join(dir, name), []byte(""), 0644)
+	}
+	os.MkdirAll(path.Join(dir, ".note"), 0755)
+	tree, err := Tree()
+	assert.Nil(t, err)
+	assert.Equal(t, ".", tree.Name)
+	assert.Equal(t, 1, tree.Notes)
+	assert.Equal(t, 4, tree.Total)
+	assert.Equal(t, []string{path.Join(dir, "a.md")}, tree.Files)
+
+	var names []string
+	var totals []int
+	tree.Walk(func(node *TreeNode) {
+		names = append(names, node.Name)
+		totals = append(totals, node.Total)
+	})
+	assert.Equal(t, []string{".", "empty", "work", "work/2026"}, names)
+	assert.Equal(t, []int{4, 0, 3, 2}, totals)
+}
@@ -0,0 +1,72 @@
+package lib
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// FuzzyScore reports whether the characters of pattern appear in text in
+// order, ignoring case, and how well they do. Consecutive characters and
+// characters starting a word or the base name score higher, gaps lower.
+func FuzzyScore(pattern, text string) (int, bool) {
+	pattern = strings.ToLower(pattern)
+	lower := strings.ToLower(text)
+	if pattern == "" {
+		return 0, true
+	}
+	base := strings.LastIndex(lower, "/") + 1
+	score := 0
+	consecutive := 0
+	prev := rune(-1)
+	next, size := utf8.DecodeRuneInString(pattern)
+	for i, r := range lower {
+		if r != next {
+			if consecutive > 0 || i >= base {
+				score--
+			}
+			consecutive = 0
+			prev = r
+			continue
+		}
+		score += 1 + 3*consecutive
+		if prev == -1 || !unicode.IsLetter(prev) && !unicode.IsDigit(prev) {
+			score += 4
+		}
+		if i >= base {
+			score += 2
+		}
+		consecutive++
+		prev = r
+		pattern = pattern[size:]
+		if pattern == "" {
+			return score, true
+		}
+		next, size = utf8.DecodeRuneInString(pattern)
+	}
+	return 0, false
+}
+
+// fuzzyMatch returns the files whose path relative to root fuzzily matches
+// name. Like List puts the most recent note last, the best match comes last
+// and files scoring the same keep their order.
+func fuzzyMatch(root, name string, files []string) []string {
+	scores := make(map[string]int)
+	var matching []string
+	for _, file := range files {
+		rel, ok := relPath(root, file)
+		if !ok {
+			rel = file
+		}
+		if score, ok := FuzzyScore(name, filepath.ToSlash(rel)); ok {
+			scores[file] = score
+			matching = append(matching, file)
+		}
+	}
+	sort.SliceStable(matching, func(i, j int) bool {
+		return scores[matching[i]] < scores[matching[j]]
+	})
+	return matching
+}
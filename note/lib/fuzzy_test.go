@@ -0,0 +1,92 @@
+package lib
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFuzzyScore(t *testing.T) {
+	_, ok := FuzzyScore("mtg", "meeting.md")
+	assert.True(t, ok)
+	_, ok = FuzzyScore("MTG", "meeting.md")
+	assert.True(t, ok)
+	_, ok = FuzzyScore("gtm", "meeting.md")
+	assert.False(t, ok)
+
+	// Consecutive characters, word starts and base names score higher.
+	consecutive, _ := FuzzyScore("meet", "meeting.md")
+	scattered, _ := FuzzyScore("meet", "my-elephant-eats-toast.md")
+	assert.True(t, consecutive > scattered)
+	wordStart, _ := FuzzyScore("wn", "work-notes.md")
+	inWord, _ := FuzzyScore("wn", "swans.md")
+	assert.True(t, wordStart > inWord)
+	base, _ := FuzzyScore("todo", "archive/todo.md")
+	dir, _ := FuzzyScore("todo", "todo/archive.md")
+	assert.True(t, base > dir)
+}
+
+func TestListFuzzy(t *testing.T) {
+	dir, _ := createTestFiles()
+	defer os.RemoveAll(dir)
+	os.Setenv("NOTES_DIR", dir)
+	os.Mkdir(path.Join(dir, "work"), 0755)
+	var files []string
+	for _, name := range []string{"meeting.md", "work/meetup.md", "my-tag.md"} {
+		file := path.Join(dir, name)
+		ioutil.WriteFile(file, nil, 0644)
+		files = append(files, file)
+	}
+	setAtime(files)
+
+	// Substring matches win over fuzzy ones.
+	returnedFileNames, _ := List("meet")
+	assert.Equal(t, files[:2], returnedFileNames)
+
+	// Without a substring match, the best fuzzy match comes last.
+	returnedFileNames, _ = List("mtg")
+	assert.Equal(t, []string{files[0], files[2]}, returnedFileNames)
+	returnedFileNames, _ = List("wkmeet")
+	assert.Equal(t, []string{files[1]}, returnedFileNames)
+	returnedFileNames, _ = List("zzz")
+	assert.Empty(t, returnedFileNames)
+
+	// Creating a note doesn't open a fuzzy match instead.
+	os.Setenv("EDITOR", "touch")
+	assert.Nil(t, EditWith("mtg", EditOptions{Create: true}))
+	_, err := os.Stat(path.Join(dir, "mtg"))
+	assert.Nil(t, err)
+	SetConfig(&Config{NotesDir: dir, Editor: "touch", Notebooks: map[string]string{"default": dir}})
+	defer SetConfig(nil)
+	assert.Nil(t, EditAll("wkmeet", EditOptions{Create: true}))
+	_, err = os.Stat(path.Join(dir, "wkmeet"))
+	assert.Nil(t, err)
+
+	// Notes are only read through fuzzy matches, never changed.
+	file, _, err := Read("mytag")
+	assert.Nil(t, err)
+	assert.Equal(t, files[2], file)
+	_, err = Remove("mytag")
+	assert.Equal(t, NoFilesError(true), err)
+	_, err = os.Stat(files[2])
+	assert.Nil(t, err)
+}
+
+func TestChoose(t *testing.T) {
+	dir, _ := createTestFiles()
+	defer os.RemoveAll(dir)
+	var choices []string
+	SetConfig(&Config{NotesDir: dir, Choose: func(files []string) (string, error) {
+		choices = files
+		return files[0], nil
+	}})
+	defer SetConfig(nil)
+
+	file, err := Resolve("file")
+	assert.Nil(t, err)
+	assert.True(t, len(choices) > 1)
+	assert.Equal(t, choices[0], file)
+}
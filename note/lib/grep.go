@@ -0,0 +1,250 @@
+package lib
+
+import (
+	"bufio"
+	"context"
+	"log"
+	"regexp"
+	"runtime"
+	"sync"
+)
+
+type GrepOptions struct {
+	// Regexp treats the pattern as a regular expression instead of a
+	// literal string.
+	Regexp     bool
+	IgnoreCase bool
+	// Before and After are the number of context lines returned around
+	// every matching line.
+	Before int
+	After  int
+	// MaxCount stops the search after that many matching lines, 0 means
+	// no limit.
+	MaxCount int
+	// Concurrency is the number of notes searched at the same time,
+	// runtime.NumCPU() when 0.
+	Concurrency int
+}
+
+// Match is a line returned by Grep. Line and Column are 1-based; Column is
+// the start of the first match on the line and 0 for context lines.
+type Match struct {
+	Notebook string
+	File     string
+	Line     int
+	Column   int
+	Text     string
+	Context  bool
+}
+
+// Matcher finds occurrences of a grep pattern in a line.
+type Matcher struct {
+	re *regexp.Regexp
+}
+
+func NewMatcher(pattern string, opts GrepOptions) (*Matcher, error) {
+	if !opts.Regexp {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if opts.IgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return &Matcher{re}, nil
+}
+
+// FindAll returns the byte offsets of all matches in line.
+func (m *Matcher) FindAll(line string) [][]int {
+	return m.re.FindAllStringIndex(line, -1)
+}
+
+// MatchedFiles returns the files of the given matches, in order and without
+// duplicates.
+func MatchedFiles(matches []Match) []string {
+	var files []string
+	seen := make(map[string]bool)
+	for _, match := range matches {
+		if !seen[match.File] {
+			seen[match.File] = true
+			files = append(files, match.File)
+		}
+	}
+	return files
+}
+
+// Grep searches the notes of the selected notebook for pattern and returns
+// the matching lines, in the order List returns the notes.
+func Grep(pattern string, opts GrepOptions) ([]Match, error) {
+	var matches []Match
+	err := GrepContext(context.Background(), pattern, opts, func(match Match) error {
+		matches = append(matches, match)
+		return nil
+	})
+	return matches, err
+}
+
+type grepResult struct {
+	matches []Match
+	err     error
+}
+
+type grepJob struct {
+	file   string
+	result chan grepResult
+}
+
+// GrepContext is like Grep but calls fn with every match as soon as it and
+// the matches before it are known. Notes are searched by a bounded pool of
+// workers; the search stops when ctx is done or fn returns an error.
+func GrepContext(ctx context.Context, pattern string, opts GrepOptions, fn func(Match) error) error {
+	matcher, err := NewMatcher(pattern, opts)
+	if err != nil {
+		return err
+	}
+	files, err := List("")
+	if err != nil {
+		return err
+	}
+	if !opts.Regexp {
+		files = indexedCandidates(files, pattern)
+	}
+	k, err := loadKeys()
+	if err != nil {
+		return err
+	}
+	workers := opts.Concurrency
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+	// pending holds the results in the order of files and bounds how far
+	// the workers get ahead of fn.
+	jobs := make(chan grepJob)
+	pending := make(chan chan grepResult, 2*workers)
+	go func() {
+		defer close(jobs)
+		defer close(pending)
+		for _, file := range files {
+			// Encrypted notes are searched only when they can be decrypted.
+			if IsEncrypted(file) && len(k.identities) == 0 {
+				continue
+			}
+			job := grepJob{file, make(chan grepResult, 1)}
+			select {
+			case pending <- job.result:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case jobs <- job:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				if ctx.Err() != nil {
+					job.result <- grepResult{err: ctx.Err()}
+					continue
+				}
+				matches, err := grepFile(job.file, matcher, opts, k)
+				job.result <- grepResult{matches, err}
+			}
+		}()
+	}
+
+	// count is the number of matching lines passed to fn so far and last
+	// the line number of the latest one.
+	count, last := 0, 0
+	for result := range pending {
+		var r grepResult
+		select {
+		case r = <-result:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if r.err != nil {
+			if r.err == ctx.Err() {
+				return r.err
+			}
+			log.Print(r.err)
+			continue
+		}
+		for _, match := range r.matches {
+			if opts.MaxCount > 0 && count == opts.MaxCount {
+				// Only the context lines after the last match remain.
+				if !match.Context || match.Line > last+opts.After {
+					return nil
+				}
+			} else if !match.Context {
+				count++
+				last = match.Line
+			}
+			if err := fn(match); err != nil {
+				return err
+			}
+		}
+		if opts.MaxCount > 0 && count == opts.MaxCount {
+			return nil
+		}
+	}
+	return ctx.Err()
+}
+
+func grepFile(file string, matcher *Matcher, opts GrepOptions, k *keys) ([]Match, error) {
+	f, err := openNote(file, k)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	var matches []Match
+	// next is the first line not yet returned, so context lines shared by
+	// two matches are returned once.
+	next := 0
+	for i, line := range lines {
+		loc := matcher.re.FindStringIndex(line)
+		if loc == nil {
+			continue
+		}
+		start := i - opts.Before
+		if start < next {
+			start = next
+		}
+		for j := start; j < i; j++ {
+			matches = append(matches, Match{File: file, Line: j + 1, Text: lines[j], Context: true})
+		}
+		matches = append(matches, Match{File: file, Line: i + 1, Column: loc[0] + 1, Text: line})
+		next = i + 1
+		for j := next; j < len(lines) && j <= i+opts.After; j++ {
+			if matcher.re.MatchString(lines[j]) {
+				break
+			}
+			matches = append(matches, Match{File: file, Line: j + 1, Text: lines[j], Context: true})
+			next = j + 1
+		}
+	}
+	return matches, nil
+}
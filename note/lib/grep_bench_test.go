@@ -0,0 +1,91 @@
+package lib
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+// grepSequential is the implementation Grep had before searching notes in
+// parallel, kept to compare against.
+func grepSequential(pattern string, opts GrepOptions) ([]Match, error) {
+	matcher, err := NewMatcher(pattern, opts)
+	if err != nil {
+		return nil, err
+	}
+	files, err := List("")
+	if err != nil {
+		return nil, err
+	}
+	k, err := loadKeys()
+	if err != nil {
+		return nil, err
+	}
+	var matches []Match
+	for _, file := range files {
+		fileMatches, err := grepFile(file, matcher, opts, k)
+		if err != nil {
+			log.Print(err)
+			continue
+		}
+		matches = append(matches, fileMatches...)
+	}
+	return matches, nil
+}
+
+// createBenchFiles writes n notes of about 16KB each, one in ten of them
+// mentioning "needle".
+func createBenchFiles(b *testing.B, n int) string {
+	dir, err := ioutil.TempDir("", "note")
+	if err != nil {
+		b.Fatal(err)
+	}
+	line := "the quick brown fox jumps over the lazy dog\n"
+	for i := 0; i < n; i++ {
+		text := strings.Repeat(line, 400)
+		if i%10 == 0 {
+			text += "a needle in the haystack\n"
+		}
+		if err := ioutil.WriteFile(path.Join(dir, fmt.Sprintf("note%04d.md", i)), []byte(text), 0644); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func benchmarkGrep(b *testing.B, grep func(string, GrepOptions) ([]Match, error), opts GrepOptions) {
+	dir := createBenchFiles(b, 500)
+	defer os.RemoveAll(dir)
+	SetConfig(&Config{NotesDir: dir, Sort: SortName})
+	defer SetConfig(nil)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		matches, err := grep("needle", opts)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(matches) == 0 {
+			b.Fatal("no matches")
+		}
+	}
+}
+
+func BenchmarkGrepSequential(b *testing.B) {
+	benchmarkGrep(b, grepSequential, GrepOptions{})
+}
+
+func BenchmarkGrep(b *testing.B) {
+	benchmarkGrep(b, Grep, GrepOptions{})
+}
+
+func BenchmarkGrepOneWorker(b *testing.B) {
+	benchmarkGrep(b, Grep, GrepOptions{Concurrency: 1})
+}
+
+func BenchmarkGrepMaxCount(b *testing.B) {
+	benchmarkGrep(b, Grep, GrepOptions{MaxCount: 1})
+}
@@ -0,0 +1,96 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGrepLines(t *testing.T) {
+	dir, files := createTestFiles()
+	defer os.RemoveAll(dir)
+	os.Setenv("NOTES_DIR", dir)
+	ioutil.WriteFile(files[5], []byte("one\ntwo Foo\nthree\nfour\nfive\nsix foo\nseven"), 0644)
+	setAtime(files)
+
+	matches, err := Grep("foo", GrepOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, []Match{{File: files[5], Line: 6, Column: 5, Text: "six foo"}}, matches)
+
+	// Case-insensitive matching with context lines.
+	matches, err = Grep("foo", GrepOptions{IgnoreCase: true, Before: 1, After: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, []Match{
+		{File: files[5], Line: 1, Text: "one", Context: true},
+		{File: files[5], Line: 2, Column: 5, Text: "two Foo"},
+		{File: files[5], Line: 3, Text: "three", Context: true},
+		{File: files[5], Line: 5, Text: "five", Context: true},
+		{File: files[5], Line: 6, Column: 5, Text: "six foo"},
+		{File: files[5], Line: 7, Text: "seven", Context: true},
+	}, matches)
+
+	// Overlapping context is returned once.
+	matches, _ = Grep("foo", GrepOptions{IgnoreCase: true, After: 5})
+	assert.Equal(t, 6, len(matches))
+
+	// Regular expressions.
+	matches, err = Grep("^t[a-z]+$", GrepOptions{Regexp: true})
+	assert.Nil(t, err)
+	assert.Equal(t, []Match{{File: files[5], Line: 3, Column: 1, Text: "three"}}, matches)
+	_, err = Grep("(", GrepOptions{Regexp: true})
+	assert.NotNil(t, err)
+}
+
+func TestGrepParallel(t *testing.T) {
+	dir, files := createTestFiles()
+	defer os.RemoveAll(dir)
+	// Reading the notes changes their atime, so keep the order stable.
+	SetConfig(&Config{NotesDir: dir, Sort: SortName})
+	defer SetConfig(nil)
+	sort.Strings(files)
+	for i, file := range files {
+		ioutil.WriteFile(file, []byte(strings.Repeat("foo\nbar\n", i+1)), 0644)
+	}
+
+	// Results keep the order of the notes whatever the concurrency.
+	sequential, err := Grep("foo", GrepOptions{Concurrency: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, 55, len(sequential))
+	for _, concurrency := range []int{0, 2, 16} {
+		matches, err := Grep("foo", GrepOptions{Concurrency: concurrency})
+		assert.Nil(t, err)
+		assert.Equal(t, sequential, matches)
+	}
+
+	// The search stops after MaxCount matching lines, keeping the context
+	// lines after the last one.
+	matches, _ := Grep("foo", GrepOptions{MaxCount: 4})
+	assert.Equal(t, sequential[:4], matches)
+	matches, _ = Grep("foo", GrepOptions{MaxCount: 2, After: 1})
+	assert.Equal(t, []Match{
+		{File: files[0], Line: 1, Column: 1, Text: "foo"},
+		{File: files[0], Line: 2, Text: "bar", Context: true},
+		{File: files[1], Line: 1, Column: 1, Text: "foo"},
+		{File: files[1], Line: 2, Text: "bar", Context: true},
+	}, matches)
+
+	// Errors returned by the callback and cancellation stop the search.
+	stop := errors.New("stop")
+	count := 0
+	err = GrepContext(context.Background(), "foo", GrepOptions{}, func(Match) error {
+		count++
+		return stop
+	})
+	assert.Equal(t, stop, err)
+	assert.Equal(t, 1, count)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = GrepContext(ctx, "foo", GrepOptions{}, func(Match) error { return nil })
+	assert.Equal(t, context.Canceled, err)
+}
@@ -0,0 +1,288 @@
+package lib
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GitError is returned when a git command run on the notes repository
+// fails.
+type GitError struct {
+	Args   []string
+	Output string
+}
+
+func (e *GitError) Error() string {
+	return "git " + strings.Join(e.Args, " ") + ": " + strings.TrimSpace(e.Output)
+}
+
+type Revision struct {
+	Hash    string    `json:"hash"`
+	Date    time.Time `json:"date"`
+	Subject string    `json:"subject"`
+}
+
+// git runs a git command on the repository in root and returns its
+// standard output. Warnings git prints to standard error only end up in
+// the error, so they never mix with the content of notes.
+func git(root string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", root}, args...)...)
+	cmd.Env = gitEnv(root)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			return "", &GitError{args, stderr.String()}
+		}
+		return "", err
+	}
+	return stdout.String(), nil
+}
+
+var (
+	identityMu sync.Mutex
+	// identities holds the identity gitEnv provides for each notebook
+	// root, empty when git has one configured.
+	identities = make(map[string][]string)
+)
+
+// gitEnv provides a committer identity when git has none configured, so
+// committing works on machines where git is only used by this tool. The
+// git config is only asked once per root.
+func gitEnv(root string) []string {
+	identityMu.Lock()
+	identity, ok := identities[root]
+	if !ok {
+		identity = gitIdentity(root)
+		identities[root] = identity
+	}
+	identityMu.Unlock()
+	return append(os.Environ(), identity...)
+}
+
+func gitIdentity(root string) []string {
+	if out, err := exec.Command("git", "-C", root, "config", "user.email").Output(); err == nil && len(bytes.TrimSpace(out)) > 0 {
+		return nil
+	}
+	name := "note"
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	host, _ := os.Hostname()
+	email := name + "@" + host
+	return []string{
+		"GIT_AUTHOR_NAME=" + name, "GIT_AUTHOR_EMAIL=" + email,
+		"GIT_COMMITTER_NAME=" + name, "GIT_COMMITTER_EMAIL=" + email,
+	}
+}
+
+// rootOf returns the notebook root containing file.
+func rootOf(file string) (string, error) {
+	conf := GetConfig()
+	roots := []string{conf.NotesDir}
+	for _, dir := range conf.Notebooks {
+		roots = append(roots, dir)
+	}
+	best := ""
+	for _, root := range roots {
+		if root == "" {
+			continue
+		}
+		if _, ok := relPath(root, file); ok && len(root) > len(best) {
+			best = root
+		}
+	}
+	if best == "" {
+		return conf.Root()
+	}
+	return best, nil
+}
+
+// ensureRepo creates the git repository of a notebook on first use.
+func ensureRepo(root string) error {
+	if _, err := os.Stat(filepath.Join(root, ".git")); err == nil {
+		return nil
+	}
+	if _, err := git(root, "init", "-q"); err != nil {
+		return err
+	}
+	exclude := filepath.Join(root, ".git", "info", "exclude")
+	if err := os.MkdirAll(filepath.Dir(exclude), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(exclude, []byte(stateDir+"/\n"+trashDir+"/\n"), 0644)
+}
+
+// commitNote commits the current content of file, if it changed. The %s in
+// format is replaced with the name of the note to form the message.
+func commitNote(file, format string) error {
+	root, err := rootOf(file)
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(root, file)
+	if err != nil {
+		return err
+	}
+	return commitFiles(root, []string{rel}, fmt.Sprintf(format, rel))
+}
+
+// isTracked reports whether rel is tracked by the git repository of the
+// notebook rooted at root.
+func isTracked(root, rel string) bool {
+	if _, err := os.Stat(filepath.Join(root, ".git")); err != nil {
+		return false
+	}
+	tracked, _ := git(root, "ls-files", "--", rel)
+	return tracked != ""
+}
+
+// commitFiles commits the current state of the given files of a notebook,
+// including their removal, in a single commit.
+func commitFiles(root string, rels []string, message string) error {
+	if err := ensureRepo(root); err != nil {
+		return err
+	}
+	// git refuses to add paths that neither exist nor are tracked.
+	var paths []string
+	for _, rel := range rels {
+		if _, err := os.Lstat(filepath.Join(root, rel)); err == nil {
+			paths = append(paths, rel)
+		} else if isTracked(root, rel) {
+			paths = append(paths, rel)
+		}
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+	rels = paths
+	args := append([]string{"add", "-A", "--"}, rels...)
+	if _, err := git(root, args...); err != nil {
+		return err
+	}
+	args = append([]string{"status", "--porcelain", "--"}, rels...)
+	status, err := git(root, args...)
+	if err != nil || strings.TrimSpace(status) == "" {
+		return err
+	}
+	args = append([]string{"commit", "-q", "-m", message, "--"}, rels...)
+	_, err = git(root, args...)
+	return err
+}
+
+func resolveInRepo(name string, fuzzy bool) (string, string, error) {
+	file, err := resolve(name, fuzzy)
+	if err != nil {
+		return "", "", err
+	}
+	root, err := rootOf(file)
+	if err != nil {
+		return "", "", err
+	}
+	if err := ensureRepo(root); err != nil {
+		return "", "", err
+	}
+	rel, err := filepath.Rel(root, file)
+	return root, rel, err
+}
+
+// Log returns the committed revisions of a note, newest first.
+func Log(name string) ([]Revision, error) {
+	root, rel, err := resolveInRepo(name, true)
+	if err != nil {
+		return nil, err
+	}
+	out, err := git(root, "log", "--follow", "--date=iso-strict", "--format=%H%x09%ad%x09%s", "--", rel)
+	if err != nil {
+		// A repository without commits has no history.
+		if _, headErr := git(root, "rev-parse", "HEAD"); headErr != nil {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var revisions []Revision
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		date, _ := time.Parse(time.RFC3339, fields[1])
+		revisions = append(revisions, Revision{fields[0], date, fields[2]})
+	}
+	return revisions, nil
+}
+
+// Diff returns the differences between the note at rev, HEAD when rev is
+// empty, and its current content.
+func Diff(name string, rev string, color bool) (string, error) {
+	root, rel, err := resolveInRepo(name, true)
+	if err != nil {
+		return "", err
+	}
+	if rev == "" {
+		rev = "HEAD"
+	}
+	colorArg := "--color=never"
+	if color {
+		colorArg = "--color=always"
+	}
+	return git(root, "diff", colorArg, rev, "--", rel)
+}
+
+// Restore replaces the content of a note with its content at rev and
+// commits the result.
+func Restore(name string, rev string) error {
+	root, rel, err := resolveInRepo(name, false)
+	if err != nil {
+		return err
+	}
+	// Keep uncommitted changes in history before overwriting them.
+	if err := commitNote(filepath.Join(root, rel), "Edit %s"); err != nil {
+		return err
+	}
+	content, err := git(root, "show", fmt.Sprintf("%s:%s", rev, filepath.ToSlash(rel)))
+	if err != nil {
+		return err
+	}
+	file := filepath.Join(root, rel)
+	if err := writeFileAtomic(file, []byte(content)); err != nil {
+		return err
+	}
+	return commitNote(file, "Restore %s to "+rev)
+}
+
+// writeFileAtomic replaces file with data so that readers see either the
+// old or the new content.
+func writeFileAtomic(file string, data []byte) error {
+	mode := os.FileMode(0644)
+	if fi, err := os.Stat(file); err == nil {
+		mode = fi.Mode().Perm()
+	}
+	f, err := ioutil.TempFile(filepath.Dir(file), "."+filepath.Base(file))
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Chmod(f.Name(), mode); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), file)
+}
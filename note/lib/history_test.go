@@ -0,0 +1,63 @@
+package lib
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistory(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir, _ := createTestFiles()
+	defer os.RemoveAll(dir)
+	SetConfig(&Config{NotesDir: dir, Editor: "touch", AutoCommit: true})
+	defer SetConfig(nil)
+
+	// Creating a note commits it.
+	err := Edit("bar", true)
+	assert.Nil(t, err)
+	revisions, err := Log("bar")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(revisions))
+	assert.Equal(t, "Edit bar", revisions[0].Subject)
+
+	// The repository is not listed as notes.
+	files, _ := List("")
+	for _, file := range files {
+		assert.False(t, strings.Contains(file, ".git"), file)
+	}
+
+	// Diff shows uncommitted changes, restore brings back old content.
+	file := path.Join(dir, "bar")
+	ioutil.WriteFile(file, []byte("version 2\n"), 0644)
+	diff, err := Diff("bar", "", false)
+	assert.Nil(t, err)
+	assert.True(t, strings.Contains(diff, "+version 2"), diff)
+	err = Restore("bar", revisions[0].Hash)
+	assert.Nil(t, err)
+	data, _ := ioutil.ReadFile(file)
+	assert.Equal(t, "", string(data))
+	revisions, _ = Log("bar")
+	assert.Equal(t, 3, len(revisions))
+	assert.True(t, strings.HasPrefix(revisions[0].Subject, "Restore bar to "))
+
+	// What git prints to stderr doesn't end up in the note.
+	os.Setenv("GIT_TRACE", "1")
+	err = Restore("bar", revisions[1].Hash)
+	os.Unsetenv("GIT_TRACE")
+	assert.Nil(t, err)
+	data, _ = ioutil.ReadFile(file)
+	assert.Equal(t, "version 2\n", string(data))
+
+	// Unknown revisions are reported.
+	err = Restore("bar", "nosuchrev")
+	_, ok := err.(*GitError)
+	assert.True(t, ok)
+}
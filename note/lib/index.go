@@ -0,0 +1,288 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/gob"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// The index maps every trigram (three lower-cased bytes) found in a note to
+// the notes containing it. A note can only contain a pattern if it contains
+// all of the pattern's trigrams, so Grep only needs to scan those notes.
+const indexVersion = 1
+
+const stateDir = ".note"
+
+type indexedFile struct {
+	Name  string
+	Mtime int64
+	Size  int64
+}
+
+type Index struct {
+	Version  int
+	Files    []indexedFile
+	Postings map[uint32][]int
+
+	root string
+	ids  map[string]int
+}
+
+func indexPath(root string) string {
+	return filepath.Join(root, stateDir, "index")
+}
+
+func trigrams(data []byte) map[uint32]bool {
+	data = bytes.ToLower(data)
+	grams := make(map[uint32]bool)
+	for i := 0; i+3 <= len(data); i++ {
+		grams[uint32(data[i])<<16|uint32(data[i+1])<<8|uint32(data[i+2])] = true
+	}
+	return grams
+}
+
+func newIndex(root string) *Index {
+	return &Index{
+		Version:  indexVersion,
+		Postings: make(map[uint32][]int),
+		root:     root,
+		ids:      make(map[string]int),
+	}
+}
+
+// loadIndex returns the index stored in root, or nil when there is none or
+// it was written by an incompatible version.
+func loadIndex(root string) *Index {
+	f, err := os.Open(indexPath(root))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	idx := newIndex(root)
+	if err := gob.NewDecoder(f).Decode(idx); err != nil || idx.Version != indexVersion {
+		return nil
+	}
+	for id, file := range idx.Files {
+		if file.Name != "" {
+			idx.ids[file.Name] = id
+		}
+	}
+	return idx
+}
+
+func (idx *Index) save() error {
+	dir := filepath.Join(idx.root, stateDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(dir, "index")
+	if err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(f).Encode(idx); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), indexPath(idx.root))
+}
+
+func (idx *Index) add(name string, fi os.FileInfo) error {
+	var data []byte
+	// The content of encrypted notes must not leak into the index, so they
+	// are always scanned.
+	if !IsEncrypted(name) {
+		var err error
+		data, err = ioutil.ReadFile(filepath.Join(idx.root, name))
+		if err != nil {
+			return err
+		}
+	}
+	id := len(idx.Files)
+	idx.Files = append(idx.Files, indexedFile{name, fi.ModTime().UnixNano(), fi.Size()})
+	idx.ids[name] = id
+	for gram := range trigrams(data) {
+		idx.Postings[gram] = append(idx.Postings[gram], id)
+	}
+	return nil
+}
+
+func (idx *Index) remove(removed map[int]bool) {
+	if len(removed) == 0 {
+		return
+	}
+	for id := range removed {
+		delete(idx.ids, idx.Files[id].Name)
+		idx.Files[id] = indexedFile{}
+	}
+	for gram, ids := range idx.Postings {
+		kept := ids[:0]
+		for _, id := range ids {
+			if !removed[id] {
+				kept = append(kept, id)
+			}
+		}
+		if len(kept) == 0 {
+			delete(idx.Postings, gram)
+		} else {
+			idx.Postings[gram] = kept
+		}
+	}
+}
+
+// refresh brings the index in line with files, re-reading only the notes
+// whose mtime or size changed. It reports whether anything changed.
+func (idx *Index) refresh(files []string) bool {
+	current := make(map[string]os.FileInfo)
+	for _, file := range files {
+		rel, err := filepath.Rel(idx.root, file)
+		if err != nil {
+			continue
+		}
+		fi, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		current[rel] = fi
+	}
+	removed := make(map[int]bool)
+	var added []string
+	for name, fi := range current {
+		id, ok := idx.ids[name]
+		if !ok {
+			added = append(added, name)
+			continue
+		}
+		entry := idx.Files[id]
+		if entry.Mtime != fi.ModTime().UnixNano() || entry.Size != fi.Size() {
+			removed[id] = true
+			added = append(added, name)
+		}
+	}
+	for name, id := range idx.ids {
+		if _, ok := current[name]; !ok {
+			removed[id] = true
+		}
+	}
+	idx.remove(removed)
+	for _, name := range added {
+		if err := idx.add(name, current[name]); err != nil {
+			log.Print(err)
+		}
+	}
+	if len(idx.Files) > 2*len(idx.ids) {
+		idx.compact()
+	}
+	return len(removed) > 0 || len(added) > 0
+}
+
+// compact drops the slots of removed files, renumbering the remaining ones.
+func (idx *Index) compact() {
+	remap := make(map[int]int)
+	var files []indexedFile
+	for id, file := range idx.Files {
+		if file.Name != "" {
+			remap[id] = len(files)
+			idx.ids[file.Name] = len(files)
+			files = append(files, file)
+		}
+	}
+	for _, ids := range idx.Postings {
+		for i, id := range ids {
+			ids[i] = remap[id]
+		}
+	}
+	idx.Files = files
+}
+
+// candidates filters files down to the ones that may contain pattern.
+func (idx *Index) candidates(files []string, pattern string) []string {
+	grams := trigrams([]byte(pattern))
+	if len(grams) == 0 {
+		return files
+	}
+	var matching map[int]bool
+	for gram := range grams {
+		next := make(map[int]bool)
+		for _, id := range idx.Postings[gram] {
+			if matching == nil || matching[id] {
+				next[id] = true
+			}
+		}
+		matching = next
+		if len(matching) == 0 {
+			break
+		}
+	}
+	var candidates []string
+	for _, file := range files {
+		rel, err := filepath.Rel(idx.root, file)
+		if err != nil {
+			continue
+		}
+		id, ok := idx.ids[rel]
+		if !ok || matching[id] || IsEncrypted(rel) {
+			candidates = append(candidates, file)
+		}
+	}
+	return candidates
+}
+
+// RebuildIndex indexes every note of the selected notebook from scratch and
+// returns the number of notes indexed.
+func RebuildIndex() (int, error) {
+	files, err := List("")
+	if err != nil {
+		return 0, err
+	}
+	root, _ := GetConfig().Root()
+	idx := newIndex(root)
+	idx.refresh(files)
+	return len(idx.ids), idx.save()
+}
+
+// indexedCandidates narrows files down using the index of the selected
+// notebook, or the one kept by its watch daemon. Without an index all files
+// are returned and have to be scanned.
+func indexedCandidates(files []string, pattern string) []string {
+	if resp := fromDaemon(daemonRequest{Op: "candidates", Pattern: pattern}); resp != nil {
+		return daemonCandidates(files, resp.Skip)
+	}
+	root, err := GetConfig().Root()
+	if err != nil {
+		return files
+	}
+	idx := loadIndex(root)
+	if idx == nil {
+		return files
+	}
+	if idx.refresh(files) {
+		if err := idx.save(); err != nil {
+			log.Print(err)
+		}
+	}
+	return idx.candidates(files, pattern)
+}
+
+// daemonCandidates drops the files the watch daemon found can't match,
+// keeping the ones it doesn't know of.
+func daemonCandidates(files, skip []string) []string {
+	skipped := make(map[string]bool)
+	for _, file := range skip {
+		skipped[file] = true
+	}
+	var kept []string
+	for _, file := range files {
+		if !skipped[file] {
+			kept = append(kept, file)
+		}
+	}
+	return kept
+}
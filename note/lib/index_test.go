@@ -0,0 +1,51 @@
+package lib
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIndex(t *testing.T) {
+	dir, files := createTestFiles()
+	defer os.RemoveAll(dir)
+	os.Setenv("NOTES_DIR", dir)
+	ioutil.WriteFile(files[1], []byte("foo is bar"), 0644)
+	ioutil.WriteFile(files[4], []byte("bar is foo"), 0644)
+	setAtime(files)
+
+	count, err := RebuildIndex()
+	assert.Nil(t, err)
+	assert.Equal(t, len(files), count)
+	_, err = os.Stat(indexPath(dir))
+	assert.Nil(t, err)
+
+	// The index directory is not listed as notes.
+	setAtime(files)
+	returnedFileNames, _ := List("")
+	assert.Equal(t, files, returnedFileNames)
+
+	// Candidates are narrowed to notes having all trigrams.
+	idx := loadIndex(dir)
+	assert.NotNil(t, idx)
+	assert.Equal(t, []string{files[1]}, idx.candidates(files, "FOO IS"))
+	assert.Equal(t, files, idx.candidates(files, "fo"))
+
+	// Changed and removed notes are picked up incrementally.
+	ioutil.WriteFile(files[7], []byte("now foo is bar too"), 0644)
+	mtime := time.Now().Add(time.Minute)
+	os.Chtimes(files[7], mtime, mtime)
+	os.Remove(files[1])
+	setAtime(files[2:])
+	matches, err := Grep("foo is bar", GrepOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{files[7]}, MatchedFiles(matches))
+	idx = loadIndex(dir)
+	_, ok := idx.ids[path.Base(files[1])]
+	assert.False(t, ok)
+	assert.Equal(t, []string{files[7]}, idx.candidates(files[2:], "foo is bar"))
+}
@@ -0,0 +1,266 @@
+package lib
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const DefaultJournalLayout = "journal/YYYY/MM/DD.md"
+
+type InvalidDateError string
+
+func (e InvalidDateError) Error() string {
+	return "Can't understand the date '" + string(e) + "'."
+}
+
+type JournalEntry struct {
+	Date time.Time
+	File string
+}
+
+var (
+	relativeDayRegex = regexp.MustCompile(`^(\d+) (day|week|month)s? ago$`)
+	futureDayRegex   = regexp.MustCompile(`^in (\d+) (day|week|month)s?$`)
+	journalLayouts   = []string{"2006-01-02", "2006/01/02", "20060102", "01-02", "Jan 2", "January 2", "2 Jan", "2 January"}
+)
+
+func day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+func addUnits(t time.Time, n int, unit string) time.Time {
+	switch unit {
+	case "week":
+		return t.AddDate(0, 0, 7*n)
+	case "month":
+		return t.AddDate(0, n, 0)
+	}
+	return t.AddDate(0, 0, n)
+}
+
+// ParseDate understands dates like "today", "yesterday", "last friday",
+// "next monday", "3 days ago", "in 2 weeks" and "2017-03-04", relative to
+// now.
+func ParseDate(s string, now time.Time) (time.Time, error) {
+	today := day(now)
+	words := strings.ToLower(strings.Join(strings.Fields(s), " "))
+	switch words {
+	case "", "today":
+		return today, nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), nil
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), nil
+	}
+	if m := relativeDayRegex.FindStringSubmatch(words); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		return addUnits(today, -n, m[2]), nil
+	}
+	if m := futureDayRegex.FindStringSubmatch(words); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		return addUnits(today, n, m[2]), nil
+	}
+	direction := -1
+	name := words
+	if strings.HasPrefix(words, "last ") {
+		name = strings.TrimPrefix(words, "last ")
+	} else if strings.HasPrefix(words, "next ") {
+		direction = 1
+		name = strings.TrimPrefix(words, "next ")
+	}
+	for wd := time.Sunday; wd <= time.Saturday; wd++ {
+		if name == strings.ToLower(wd.String()) || name == strings.ToLower(wd.String()[:3]) {
+			t := today.AddDate(0, 0, direction)
+			for t.Weekday() != wd {
+				t = t.AddDate(0, 0, direction)
+			}
+			return t, nil
+		}
+	}
+	for _, layout := range journalLayouts {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			if t.Year() == 0 {
+				t = t.AddDate(now.Year(), 0, 0)
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, InvalidDateError(s)
+}
+
+func journalLayout() string {
+	if layout := GetConfig().JournalLayout; layout != "" {
+		return layout
+	}
+	return DefaultJournalLayout
+}
+
+// JournalName returns the name, relative to the notebook root, of the
+// journal entry of the given day.
+func JournalName(date time.Time) string {
+	r := strings.NewReplacer(
+		"YYYY", date.Format("2006"),
+		"MM", date.Format("01"),
+		"DD", date.Format("02"))
+	return filepath.FromSlash(r.Replace(journalLayout()))
+}
+
+// JournalEntries returns the journal entries from "from" to "to", both
+// included, oldest first. Zero times leave the range open.
+func JournalEntries(from, to time.Time) ([]JournalEntry, error) {
+	root, err := GetConfig().Root()
+	if err != nil {
+		return nil, err
+	}
+	files, err := List("")
+	if err != nil {
+		return nil, err
+	}
+	pattern := regexp.QuoteMeta(filepath.FromSlash(journalLayout()))
+	pattern = strings.NewReplacer("YYYY", `(?P<y>\d{4})`, "MM", `(?P<m>\d{2})`, "DD", `(?P<d>\d{2})`).Replace(pattern)
+	layoutRegex, err := regexp.Compile("^" + pattern + "$")
+	if err != nil {
+		return nil, err
+	}
+	var entries []JournalEntry
+	for _, file := range files {
+		rel, err := filepath.Rel(root, file)
+		if err != nil {
+			continue
+		}
+		m := layoutRegex.FindStringSubmatch(rel)
+		if m == nil {
+			continue
+		}
+		parts := make(map[string]int)
+		for i, name := range layoutRegex.SubexpNames() {
+			if name != "" {
+				parts[name], _ = strconv.Atoi(m[i])
+			}
+		}
+		date := time.Date(parts["y"], time.Month(parts["m"]), parts["d"], 0, 0, 0, 0, time.Local)
+		if (!from.IsZero() && date.Before(day(from))) || (!to.IsZero() && date.After(day(to))) {
+			continue
+		}
+		entries = append(entries, JournalEntry{date, file})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Date.Before(entries[j].Date)
+	})
+	return entries, nil
+}
+
+// linkName returns the wiki link target of a note.
+func linkName(root, file string) string {
+	rel, err := filepath.Rel(root, file)
+	if err != nil {
+		rel = filepath.Base(file)
+	}
+	return filepath.ToSlash(strings.TrimSuffix(rel, filepath.Ext(rel)))
+}
+
+// setLinkLine sets the "label: [[target]]" line of a note, replacing an
+// existing one. Other lines starting with the label are the user's own
+// text and are left alone.
+func setLinkLine(file, label, target string) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	line := label + ": [[" + target + "]]"
+	linkLine := regexp.MustCompile(`^` + regexp.QuoteMeta(label) + `: \[\[[^\[\]]*\]\]\s*$`)
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	replaced := false
+	for i, l := range lines {
+		if linkLine.MatchString(l) {
+			lines[i] = line
+			replaced = true
+		}
+	}
+	if !replaced {
+		if len(lines) == 1 && lines[0] == "" {
+			lines = nil
+		}
+		lines = append(lines, line)
+	}
+	return writeFileAtomic(file, []byte(strings.Join(lines, "\n")+"\n"))
+}
+
+// linkJournalEntry links a new entry to the entries before and after it.
+func linkJournalEntry(root, file string, date time.Time) error {
+	entries, err := JournalEntries(time.Time{}, time.Time{})
+	if err != nil {
+		return err
+	}
+	var prev, next *JournalEntry
+	for i := range entries {
+		if entries[i].Date.Before(date) {
+			prev = &entries[i]
+		} else if entries[i].Date.After(date) && next == nil {
+			next = &entries[i]
+		}
+	}
+	name := linkName(root, file)
+	link := func(from *JournalEntry, fromLabel, toLabel string) error {
+		if from == nil {
+			return nil
+		}
+		if err := setLinkLine(file, toLabel, linkName(root, from.File)); err != nil {
+			return err
+		}
+		if err := setLinkLine(from.File, fromLabel, name); err != nil {
+			return err
+		}
+		if GetConfig().AutoCommit {
+			if err := commitNote(from.File, "Link %s"); err != nil {
+				log.Print(err)
+			}
+		}
+		return nil
+	}
+	if err := link(prev, "Next", "Previous"); err != nil {
+		return err
+	}
+	return link(next, "Previous", "Next")
+}
+
+// Journal opens the journal entry of the given day in the editor. Missing
+// entries are created from the journal template, when there is one, and
+// linked to the previous and next entries.
+func Journal(date time.Time, opts EditOptions) error {
+	conf := GetConfig()
+	editor := strings.Fields(conf.Editor)
+	if len(editor) == 0 {
+		return EditorNotSetError(true)
+	}
+	root, err := conf.Root()
+	if err != nil {
+		return err
+	}
+	name := JournalName(date)
+	file := filepath.Join(root, name)
+	if _, err := os.Stat(file); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			return err
+		}
+		if opts.Template == "" && conf.JournalTemplate != "" {
+			if _, err := TemplateFile(conf.JournalTemplate); err == nil {
+				opts.Template = conf.JournalTemplate
+			}
+		}
+		if err := createNote(file, name, opts); err != nil {
+			return err
+		}
+		if err := linkJournalEntry(root, file, date); err != nil {
+			return err
+		}
+	}
+	return editMatching(editor, []string{file}, name, opts)
+}
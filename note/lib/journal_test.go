@@ -0,0 +1,74 @@
+package lib
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDate(t *testing.T) {
+	// A Wednesday.
+	now := time.Date(2017, time.March, 8, 15, 4, 5, 0, time.UTC)
+	tests := map[string]string{
+		"":            "2017-03-08",
+		"Today":       "2017-03-08",
+		"yesterday":   "2017-03-07",
+		"tomorrow":    "2017-03-09",
+		"last friday": "2017-03-03",
+		"friday":      "2017-03-03",
+		"last wed":    "2017-03-01",
+		"next monday": "2017-03-13",
+		"3 days ago":  "2017-03-05",
+		"1 week ago":  "2017-03-01",
+		"in 2 days":   "2017-03-10",
+		"2016-12-31":  "2016-12-31",
+		"Jan 2":       "2017-01-02",
+	}
+	for input, expected := range tests {
+		date, err := ParseDate(input, now)
+		assert.Nil(t, err, input)
+		assert.Equal(t, expected, date.Format("2006-01-02"), input)
+	}
+	_, err := ParseDate("someday", now)
+	assert.Equal(t, InvalidDateError("someday"), err)
+}
+
+func TestJournal(t *testing.T) {
+	dir, _ := createTestFiles()
+	defer os.RemoveAll(dir)
+	SetConfig(&Config{NotesDir: dir, Editor: "true", JournalTemplate: "journal"})
+	defer SetConfig(nil)
+	templatesDir, _ := TemplatesDir()
+	os.MkdirAll(templatesDir, 0755)
+	ioutil.WriteFile(path.Join(templatesDir, "journal.md"), []byte("# {{.Title}}\n"), 0644)
+
+	first := time.Date(2017, time.March, 1, 0, 0, 0, 0, time.Local)
+	third := first.AddDate(0, 0, 2)
+	second := first.AddDate(0, 0, 1)
+	for _, date := range []time.Time{first, third, second} {
+		assert.Nil(t, Journal(date, EditOptions{}))
+	}
+	read := func(name string) string {
+		data, _ := ioutil.ReadFile(path.Join(dir, name))
+		return string(data)
+	}
+	assert.Equal(t, "# 01\nNext: [[journal/2017/03/02]]\n", read("journal/2017/03/01.md"))
+	assert.Equal(t, "# 02\nPrevious: [[journal/2017/03/01]]\nNext: [[journal/2017/03/03]]\n", read("journal/2017/03/02.md"))
+	assert.Equal(t, "# 03\nPrevious: [[journal/2017/03/02]]\n", read("journal/2017/03/03.md"))
+
+	entries, err := JournalEntries(second, time.Time{})
+	assert.Nil(t, err)
+	assert.Equal(t, []JournalEntry{
+		{second, path.Join(dir, "journal/2017/03/02.md")},
+		{third, path.Join(dir, "journal/2017/03/03.md")},
+	}, entries)
+
+	// Lines the user wrote starting with a label are kept.
+	ioutil.WriteFile(path.Join(dir, "journal/2017/03/03.md"), []byte("# 03\nNext: call Bob about the release\n"), 0644)
+	assert.Nil(t, Journal(first.AddDate(0, 0, 3), EditOptions{}))
+	assert.Equal(t, "# 03\nNext: call Bob about the release\nNext: [[journal/2017/03/04]]\n", read("journal/2017/03/03.md"))
+}
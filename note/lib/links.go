@@ -0,0 +1,245 @@
+package lib
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	wikiLinkRegex     = regexp.MustCompile(`\[\[([^\[\]|#]+)(#[^\[\]|]*)?(\|[^\[\]]*)?\]\]`)
+	markdownLinkRegex = regexp.MustCompile(`\[[^\[\]]*\]\(([^()\s]+)(\s+"[^"]*")?\)`)
+	externalLinkRegex = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*:`)
+)
+
+const (
+	WikiLink     = "wiki"
+	MarkdownLink = "markdown"
+)
+
+// Link is a reference to another note. Start and End are the byte offsets
+// of Target in the line.
+type Link struct {
+	Kind   string
+	Target string
+	Line   int
+	Start  int
+	End    int
+	Text   string
+}
+
+// ResolvedLink is a link found in File, pointing to the note To. To is
+// empty when the link doesn't resolve to exactly one note.
+type ResolvedLink struct {
+	Link
+	File string
+	To   string
+}
+
+// ParseLinks returns the wiki and markdown links to other notes in text,
+// skipping fenced code blocks and external URLs.
+func ParseLinks(text string) []Link {
+	var links []Link
+	inCode := false
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := scanner.Text()
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			continue
+		}
+		for _, m := range wikiLinkRegex.FindAllStringSubmatchIndex(line, -1) {
+			links = append(links, Link{WikiLink, strings.TrimSpace(line[m[2]:m[3]]), lineNum, m[2], m[3], line})
+		}
+		for _, m := range markdownLinkRegex.FindAllStringSubmatchIndex(line, -1) {
+			target := line[m[2]:m[3]]
+			if externalLinkRegex.MatchString(target) || strings.HasPrefix(target, "#") {
+				continue
+			}
+			end := m[3]
+			if i := strings.Index(target, "#"); i >= 0 {
+				target = target[:i]
+				end = m[2] + i
+			}
+			if unescaped, err := url.PathUnescape(target); err == nil {
+				target = unescaped
+			}
+			links = append(links, Link{MarkdownLink, target, lineNum, m[2], end, line})
+		}
+	}
+	sort.SliceStable(links, func(i, j int) bool {
+		if links[i].Line != links[j].Line {
+			return links[i].Line < links[j].Line
+		}
+		return links[i].Start < links[j].Start
+	})
+	return links
+}
+
+func withoutExt(name string) string {
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+// resolveLink finds the note a link in file points to among files. Markdown
+// links are first tried as paths relative to file. Otherwise the target is
+// matched like List matches names, preferring an exact name when several
+// notes match.
+func resolveLink(link Link, file, root string, files []string) string {
+	if link.Kind == MarkdownLink {
+		candidate := filepath.Join(filepath.Dir(file), filepath.FromSlash(link.Target))
+		for _, f := range files {
+			if f == candidate {
+				return f
+			}
+		}
+	}
+	// Like names given to List, targets are matched against the path
+	// relative to the root, so the directories above it never match.
+	target := link.Target
+	var matching, rels []string
+	for _, f := range files {
+		rel, ok := relPath(root, f)
+		if ok && strings.Contains(filepath.ToSlash(rel), target) {
+			matching = append(matching, f)
+			rels = append(rels, filepath.ToSlash(rel))
+		}
+	}
+	if len(matching) == 1 {
+		return matching[0]
+	}
+	var exact []string
+	for i, f := range matching {
+		rel := rels[i]
+		if rel == target || withoutExt(rel) == target || withoutExt(filepath.Base(f)) == target {
+			exact = append(exact, f)
+		}
+	}
+	if len(exact) == 1 {
+		return exact[0]
+	}
+	return ""
+}
+
+// linkGraph returns the resolved outgoing links of every note of the
+// selected notebook, along with the notes.
+func linkGraph() (map[string][]ResolvedLink, []string, error) {
+	files, err := List("")
+	if err != nil {
+		return nil, nil, err
+	}
+	root, _ := GetConfig().Root()
+	links, err := noteLinks(files)
+	if err != nil {
+		return nil, nil, err
+	}
+	graph := make(map[string][]ResolvedLink)
+	for _, file := range files {
+		for _, link := range links[file] {
+			to := resolveLink(link, file, root, files)
+			graph[file] = append(graph[file], ResolvedLink{link, file, to})
+		}
+	}
+	return graph, files, nil
+}
+
+// noteLinks returns the unresolved links of files, asking the watch daemon
+// when one is running. Notes that can't be read are left out.
+func noteLinks(files []string) (map[string][]Link, error) {
+	if resp := fromDaemon(daemonRequest{Op: "links"}); resp != nil {
+		return resp.Links, nil
+	}
+	k, err := loadKeys()
+	if err != nil {
+		return nil, err
+	}
+	links := make(map[string][]Link)
+	for _, file := range files {
+		if fileLinks, err := fileLinks(file, k); err == nil {
+			links[file] = fileLinks
+		}
+	}
+	return links, nil
+}
+
+func fileLinks(file string, k *keys) ([]Link, error) {
+	if IsEncrypted(file) && len(k.identities) == 0 {
+		return nil, NoKeyError(true)
+	}
+	f, err := openNote(file, k)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	return ParseLinks(string(data)), nil
+}
+
+// Links returns the links of the note matching name.
+func Links(name string) ([]ResolvedLink, error) {
+	file, err := resolve(name, true)
+	if err != nil {
+		return nil, err
+	}
+	graph, _, err := linkGraph()
+	if err != nil {
+		return nil, err
+	}
+	return graph[file], nil
+}
+
+// Backlinks returns the links pointing to the note matching name.
+func Backlinks(name string) ([]ResolvedLink, error) {
+	file, err := resolve(name, true)
+	if err != nil {
+		return nil, err
+	}
+	graph, files, err := linkGraph()
+	if err != nil {
+		return nil, err
+	}
+	var backlinks []ResolvedLink
+	for _, from := range files {
+		for _, link := range graph[from] {
+			if link.To == file && from != file {
+				backlinks = append(backlinks, link)
+			}
+		}
+	}
+	return backlinks, nil
+}
+
+// Orphans returns the notes that neither link to other notes nor are
+// linked from them.
+func Orphans() ([]string, error) {
+	graph, files, err := linkGraph()
+	if err != nil {
+		return nil, err
+	}
+	linked := make(map[string]bool)
+	for from, links := range graph {
+		for _, link := range links {
+			if link.To != "" && link.To != from {
+				linked[from] = true
+				linked[link.To] = true
+			}
+		}
+	}
+	var orphans []string
+	for _, file := range files {
+		if !linked[file] {
+			orphans = append(orphans, file)
+		}
+	}
+	return orphans, nil
+}
@@ -0,0 +1,63 @@
+package lib
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLinks(t *testing.T) {
+	text := "see [[foo]] and [[bar/baz#intro|Baz]]\n" +
+		"```\n[[not-a-link]]\n```\n" +
+		"[doc](../docs/my%20doc.md#top) [web](https://example.com) [top](#top)\n"
+	links := ParseLinks(text)
+	assert.Equal(t, 3, len(links))
+	assert.Equal(t, Link{WikiLink, "foo", 1, 6, 9, "see [[foo]] and [[bar/baz#intro|Baz]]"}, links[0])
+	assert.Equal(t, "bar/baz", links[1].Target)
+	assert.Equal(t, "bar/baz", links[1].Text[links[1].Start:links[1].End])
+	assert.Equal(t, MarkdownLink, links[2].Kind)
+	assert.Equal(t, "../docs/my doc.md", links[2].Target)
+	assert.Equal(t, 5, links[2].Line)
+	assert.Equal(t, "../docs/my%20doc.md", links[2].Text[links[2].Start:links[2].End])
+}
+
+func TestBacklinks(t *testing.T) {
+	dir, files := createTestFiles()
+	defer os.RemoveAll(dir)
+	os.Setenv("NOTES_DIR", dir)
+	os.Mkdir(path.Join(dir, "sub"), 0755)
+	ioutil.WriteFile(path.Join(dir, "kafka.md"), []byte("about [[sub/pulsar]]\n"), 0644)
+	ioutil.WriteFile(path.Join(dir, "sub", "pulsar.md"), []byte("see [kafka](../kafka.md) and [[missing]]\n"), 0644)
+	ioutil.WriteFile(path.Join(dir, "sub", "pulsar-old.md"), []byte("[[kafka]]\n"), 0644)
+
+	links, err := Links("pulsar.md")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(links))
+	assert.Equal(t, path.Join(dir, "kafka.md"), links[0].To)
+	assert.Equal(t, "", links[1].To)
+
+	// Several notes match "sub/pulsar" but only one exactly.
+	backlinks, err := Backlinks("pulsar.md")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(backlinks))
+	assert.Equal(t, path.Join(dir, "kafka.md"), backlinks[0].File)
+
+	backlinks, err = Backlinks("kafka")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(backlinks))
+
+	orphans, err := Orphans()
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, files, orphans)
+
+	// Targets only match the path below the root, which for the test
+	// notes starts with "note" too.
+	ideas := path.Join(dir, "notebook-ideas.md")
+	ioutil.WriteFile(ideas, []byte("more [[note]]\n"), 0644)
+	links, err = Links("notebook-ideas")
+	assert.Nil(t, err)
+	assert.Equal(t, ideas, links[0].To)
+}
@@ -0,0 +1,37 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+const lockFile = "lock"
+
+// lockNotebook takes an exclusive lock on the notebook rooted at root,
+// waiting while another process holds it, and returns the function
+// releasing it. The lock is a flock(2) on a file of the state directory
+// rather than on a note, since notes are replaced when written.
+func lockNotebook(root string) (func(), error) {
+	if err := os.MkdirAll(filepath.Join(root, stateDir), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(filepath.Join(root, stateDir, lockFile), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+		if err != syscall.EINTR {
+			break
+		}
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
@@ -0,0 +1,273 @@
+package lib
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Meta is the front matter of a note: a YAML block between "---" lines or
+// a TOML block between "+++" lines at the very top of the file.
+type Meta struct {
+	Title   string
+	Tags    []string
+	Created time.Time
+	Aliases []string
+}
+
+// frontMatterLimit bounds how much of a note is read looking for the end
+// of its front matter.
+const frontMatterLimit = 64 * 1024
+
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+func ParseMeta(file string) (*Meta, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readMeta(f)
+}
+
+func readMeta(r io.Reader) (*Meta, error) {
+	block, delim, err := readFrontMatter(r)
+	if err != nil || block == nil {
+		return &Meta{}, err
+	}
+	fields := make(map[string]interface{})
+	if delim == "+++" {
+		err = toml.Unmarshal(block, &fields)
+	} else {
+		err = yaml.Unmarshal(block, &fields)
+	}
+	if err != nil {
+		return &Meta{}, err
+	}
+	meta := &Meta{
+		Title:   stringField(fields["title"]),
+		Tags:    listField(fields["tags"]),
+		Aliases: listField(fields["aliases"]),
+	}
+	for i, tag := range meta.Tags {
+		meta.Tags[i] = strings.TrimPrefix(tag, "#")
+	}
+	created := fields["created"]
+	if created == nil {
+		created = fields["date"]
+	}
+	meta.Created = timeField(created)
+	return meta, nil
+}
+
+// SplitFrontMatter returns the front matter of a note and the rest of its
+// text. Notes without front matter are returned whole.
+func SplitFrontMatter(data []byte) (*Meta, []byte) {
+	meta, err := readMeta(bytes.NewReader(data))
+	if err != nil {
+		meta = &Meta{}
+	}
+	first := bytes.IndexByte(data, '\n')
+	if first < 0 {
+		return meta, data
+	}
+	delim := strings.TrimSpace(string(data[:first]))
+	if delim != "---" && delim != "+++" {
+		return meta, data
+	}
+	for pos := first + 1; pos < len(data); {
+		end := bytes.IndexByte(data[pos:], '\n')
+		if end < 0 {
+			end = len(data)
+		} else {
+			end += pos + 1
+		}
+		trimmed := strings.TrimSpace(string(data[pos:end]))
+		if trimmed == delim || (delim == "---" && trimmed == "...") {
+			return meta, data[end:]
+		}
+		pos = end
+	}
+	return meta, data
+}
+
+// readFrontMatter returns the front matter block of r without its
+// delimiters, or nil when r has none.
+func readFrontMatter(r io.Reader) ([]byte, string, error) {
+	reader := bufio.NewReader(io.LimitReader(r, frontMatterLimit))
+	first, err := reader.ReadString('\n')
+	delim := strings.TrimSpace(first)
+	if err != nil || (delim != "---" && delim != "+++") {
+		return nil, "", nil
+	}
+	var block bytes.Buffer
+	for {
+		line, err := reader.ReadString('\n')
+		trimmed := strings.TrimSpace(line)
+		if trimmed == delim || (delim == "---" && trimmed == "...") {
+			return block.Bytes(), delim, nil
+		}
+		block.WriteString(line)
+		if err == io.EOF {
+			return nil, "", nil
+		} else if err != nil {
+			return nil, "", err
+		}
+	}
+}
+
+func stringField(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+// listField accepts both lists and comma separated strings.
+func listField(value interface{}) []string {
+	var items []string
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			items = append(items, strings.TrimSpace(fmt.Sprint(item)))
+		}
+	case string:
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
+
+func timeField(value interface{}) time.Time {
+	switch v := value.(type) {
+	case time.Time:
+		return v
+	case nil:
+		return time.Time{}
+	}
+	s := stringField(value)
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (m *Meta) HasTag(tag string) bool {
+	for _, t := range m.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter selects notes by their front matter.
+type Filter struct {
+	Tags []string
+	// AnyTag selects notes having any of Tags instead of all of them.
+	AnyTag bool
+}
+
+func (f Filter) empty() bool {
+	return len(f.Tags) == 0
+}
+
+func (f Filter) Match(meta *Meta) bool {
+	for _, tag := range f.Tags {
+		has := meta.HasTag(tag)
+		if f.AnyTag && has {
+			return true
+		}
+		if !f.AnyTag && !has {
+			return false
+		}
+	}
+	return !f.AnyTag || len(f.Tags) == 0
+}
+
+// ListWith is like List but only returns the notes whose front matter
+// matches filter.
+func ListWith(name string, filter Filter) ([]string, error) {
+	return listWith(name, filter, true)
+}
+
+func listWith(name string, filter Filter, fuzzy bool) ([]string, error) {
+	files, err := list(name, fuzzy)
+	if err != nil || filter.empty() {
+		return files, err
+	}
+	tags := noteTags(files)
+	var matching []string
+	for _, file := range files {
+		fileTags, ok := tags[file]
+		if ok && filter.Match(&Meta{Tags: fileTags}) {
+			matching = append(matching, file)
+		}
+	}
+	return matching, nil
+}
+
+// noteTags returns the tags of files, asking the watch daemon when one is
+// running. Notes whose front matter can't be parsed are left out.
+func noteTags(files []string) map[string][]string {
+	if resp := fromDaemon(daemonRequest{Op: "tags"}); resp != nil {
+		return resp.Tags
+	}
+	tags := make(map[string][]string)
+	for _, file := range files {
+		if meta, err := ParseMeta(file); err == nil {
+			tags[file] = meta.Tags
+		}
+	}
+	return tags
+}
+
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// Tags returns every tag used in the selected notebook with the number of
+// notes using it, most used first.
+func Tags() ([]TagCount, error) {
+	files, err := List("")
+	if err != nil {
+		return nil, err
+	}
+	fileTags := noteTags(files)
+	counts := make(map[string]int)
+	for _, file := range files {
+		for _, tag := range fileTags[file] {
+			counts[strings.ToLower(tag)]++
+		}
+	}
+	var tags []TagCount
+	for tag, count := range counts {
+		tags = append(tags, TagCount{tag, count})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Count != tags[j].Count {
+			return tags[i].Count > tags[j].Count
+		}
+		return tags[i].Tag < tags[j].Tag
+	})
+	return tags, nil
+}
@@ -0,0 +1,79 @@
+package lib
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadMeta(t *testing.T) {
+	yamlNote := "---\ntitle: Retro\ntags: [work, \"#kafka\"]\ncreated: 2017-03-04\naliases:\n  - retro-notes\n---\nbody\n"
+	meta, err := readMeta(strings.NewReader(yamlNote))
+	assert.Nil(t, err)
+	assert.Equal(t, "Retro", meta.Title)
+	assert.Equal(t, []string{"work", "kafka"}, meta.Tags)
+	assert.Equal(t, []string{"retro-notes"}, meta.Aliases)
+	assert.Equal(t, 2017, meta.Created.Year())
+
+	tomlNote := "+++\ntitle = \"Plan\"\ntags = \"work, home\"\ndate = 2018-01-02\n+++\nbody\n"
+	meta, err = readMeta(strings.NewReader(tomlNote))
+	assert.Nil(t, err)
+	assert.Equal(t, "Plan", meta.Title)
+	assert.Equal(t, []string{"work", "home"}, meta.Tags)
+	assert.Equal(t, time.January, meta.Created.Month())
+
+	// Notes without or with unterminated front matter have no metadata.
+	meta, err = readMeta(strings.NewReader("just text\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, &Meta{}, meta)
+	meta, err = readMeta(strings.NewReader("---\ntags: [work]\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, &Meta{}, meta)
+}
+
+func TestListWithTags(t *testing.T) {
+	dir, files := createTestFiles()
+	defer os.RemoveAll(dir)
+	os.Setenv("NOTES_DIR", dir)
+	ioutil.WriteFile(files[1], []byte("---\ntags: [work, kafka]\n---\n"), 0644)
+	ioutil.WriteFile(files[2], []byte("---\ntags: [Work]\n---\n"), 0644)
+	ioutil.WriteFile(files[3], []byte("---\ntags: [home]\n---\n"), 0644)
+	setAtime(files)
+
+	returnedFileNames, err := ListWith("", Filter{Tags: []string{"work"}})
+	assert.Nil(t, err)
+	assert.Equal(t, files[1:3], returnedFileNames)
+
+	setAtime(files)
+	returnedFileNames, _ = ListWith("", Filter{Tags: []string{"work", "kafka"}})
+	assert.Equal(t, files[1:2], returnedFileNames)
+
+	setAtime(files)
+	returnedFileNames, _ = ListWith("", Filter{Tags: []string{"kafka", "home"}, AnyTag: true})
+	assert.Equal(t, []string{files[1], files[3]}, returnedFileNames)
+
+	tags, err := Tags()
+	assert.Nil(t, err)
+	assert.Equal(t, []TagCount{{"work", 2}, {"home", 1}, {"kafka", 1}}, tags)
+}
+
+func TestSplitFrontMatter(t *testing.T) {
+	meta, body := SplitFrontMatter([]byte("---\ntitle: Retro\n---\n# Notes\nbody\n"))
+	assert.Equal(t, "Retro", meta.Title)
+	assert.Equal(t, "# Notes\nbody\n", string(body))
+
+	meta, body = SplitFrontMatter([]byte("+++\ntitle = \"Retro\"\n+++\nbody"))
+	assert.Equal(t, "Retro", meta.Title)
+	assert.Equal(t, "body", string(body))
+
+	// Notes without front matter, or with an unterminated one, are whole.
+	for _, note := range []string{"body\n", "---\ntitle: Retro\nbody\n", "---"} {
+		meta, body = SplitFrontMatter([]byte(note))
+		assert.Equal(t, "", meta.Title)
+		assert.Equal(t, note, string(body))
+	}
+}
@@ -0,0 +1,238 @@
+package lib
+
+import (
+	"io/ioutil"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type TargetExistsError string
+
+func (e TargetExistsError) Error() string {
+	return "'" + string(e) + "' already exists."
+}
+
+// Rewrite is a line changed to keep a link pointing at a moved note. File
+// is where the line ends up after the move.
+type Rewrite struct {
+	File string
+	Line int
+	Old  string
+	New  string
+}
+
+type MoveResult struct {
+	From     string
+	To       string
+	Rewrites []Rewrite
+}
+
+// readNote and writeNote read and write the plain text of a note,
+// decrypting and encrypting it when needed.
+func readNote(file string, k *keys) ([]byte, error) {
+	if IsEncrypted(file) {
+		return k.decrypt(file)
+	}
+	return ioutil.ReadFile(file)
+}
+
+func writeNote(file string, data []byte, k *keys) error {
+	if IsEncrypted(file) {
+		return k.encrypt(file, data)
+	}
+	return writeFileAtomic(file, data)
+}
+
+// moveTarget returns the file a note is moved to. Moving into an existing
+// directory keeps the base name and a missing extension is taken from the
+// note.
+func moveTarget(root, file, newName string) (string, error) {
+	target, err := notePath(root, newName)
+	if err != nil {
+		return "", err
+	}
+	if fi, err := os.Stat(target); err == nil && fi.IsDir() {
+		return filepath.Join(target, filepath.Base(file)), nil
+	}
+	if filepath.Ext(target) == "" {
+		target += filepath.Ext(file)
+	}
+	return target, nil
+}
+
+// relativeLink returns the markdown link target for file seen from the
+// note from.
+func relativeLink(from, file string) string {
+	rel, err := filepath.Rel(filepath.Dir(from), file)
+	if err != nil {
+		rel = file
+	}
+	return (&url.URL{Path: filepath.ToSlash(rel)}).EscapedPath()
+}
+
+// newLinkTarget returns what a link to a note moved to newFile should say,
+// keeping the style of the old link.
+func newLinkTarget(link ResolvedLink, root, newFile, newFrom string, files []string) string {
+	if link.Kind == MarkdownLink {
+		return relativeLink(newFrom, newFile)
+	}
+	target := linkName(root, newFile)
+	if filepath.Ext(link.Target) != "" {
+		target += filepath.Ext(newFile)
+	}
+	// Keep short links short when the base name is enough to find the note.
+	if !strings.Contains(link.Target, "/") {
+		short := filepath.Base(target)
+		if resolveLink(Link{Kind: WikiLink, Target: short}, newFrom, root, files) == newFile {
+			return short
+		}
+	}
+	return target
+}
+
+// Move renames the note matching oldName to newName, relative to the
+// notebook root, and rewrites the links of other notes pointing to it. With
+// dryRun nothing is changed and only the result is returned.
+func Move(oldName, newName string, dryRun bool) (*MoveResult, error) {
+	file, err := Resolve(oldName)
+	if err != nil {
+		return nil, err
+	}
+	root, err := rootOf(file)
+	if err != nil {
+		return nil, err
+	}
+	newFile, err := moveTarget(root, file, newName)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Lstat(newFile); err == nil {
+		return nil, TargetExistsError(newFile)
+	}
+	graph, files, err := linkGraph()
+	if err != nil {
+		return nil, err
+	}
+	// Resolve the new links against the notes as they'll be after the move.
+	var movedFiles []string
+	for _, f := range files {
+		if f == file {
+			f = newFile
+		}
+		movedFiles = append(movedFiles, f)
+	}
+	moved := func(f string) string {
+		if f == file {
+			return newFile
+		}
+		return f
+	}
+
+	result := &MoveResult{From: file, To: newFile}
+	// The graph may be the watch daemon's and older than the notes, so it
+	// only tells which notes to open. Their links are parsed again from
+	// the text read, which the offsets rewritten must come from.
+	opened := make(map[string]bool)
+	for from, links := range graph {
+		for _, link := range links {
+			if link.To == file || (from == file && link.To != "") {
+				opened[from] = true
+			}
+		}
+	}
+	k, err := loadKeys()
+	if err != nil {
+		return nil, err
+	}
+	contents := make(map[string][]byte)
+	var changedFiles []string
+	for from := range opened {
+		changedFiles = append(changedFiles, from)
+	}
+	sort.Strings(changedFiles)
+	for _, from := range changedFiles {
+		data, err := readNote(from, k)
+		if err != nil {
+			return nil, err
+		}
+		var links []ResolvedLink
+		for _, link := range ParseLinks(string(data)) {
+			to := resolveLink(link, from, root, files)
+			// Relative links of the moved note change with its directory.
+			if to != "" && (to == file || (from == file && link.Kind == MarkdownLink)) {
+				links = append(links, ResolvedLink{link, from, to})
+			}
+		}
+		lines := strings.Split(string(data), "\n")
+		// Replace from the end so earlier offsets in a line stay valid.
+		sort.Slice(links, func(i, j int) bool {
+			if links[i].Line != links[j].Line {
+				return links[i].Line > links[j].Line
+			}
+			return links[i].Start > links[j].Start
+		})
+		oldLines := make(map[int]string)
+		for _, link := range links {
+			idx := link.Line - 1
+			target := newLinkTarget(link, root, moved(link.To), moved(from), movedFiles)
+			line := lines[idx]
+			if line[link.Start:link.End] == target {
+				continue
+			}
+			if _, ok := oldLines[link.Line]; !ok {
+				oldLines[link.Line] = line
+			}
+			lines[idx] = line[:link.Start] + target + line[link.End:]
+		}
+		var lineNums []int
+		for lineNum := range oldLines {
+			lineNums = append(lineNums, lineNum)
+		}
+		sort.Ints(lineNums)
+		for _, lineNum := range lineNums {
+			result.Rewrites = append(result.Rewrites, Rewrite{moved(from), lineNum, oldLines[lineNum], lines[lineNum-1]})
+		}
+		if len(lineNums) > 0 {
+			contents[from] = []byte(strings.Join(lines, "\n"))
+		}
+	}
+	if dryRun {
+		return result, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(newFile), 0755); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(file, newFile); err != nil {
+		return nil, err
+	}
+	if err := moveAccess(root, file, newFile); err != nil {
+		log.Print(err)
+	}
+	rels := []string{}
+	for _, f := range []string{file, newFile} {
+		rel, _ := filepath.Rel(root, f)
+		rels = append(rels, rel)
+	}
+	for _, from := range changedFiles {
+		data, ok := contents[from]
+		if !ok {
+			continue
+		}
+		if err := writeNote(moved(from), data, k); err != nil {
+			return result, err
+		}
+		rel, _ := filepath.Rel(root, moved(from))
+		rels = append(rels, rel)
+	}
+	if GetConfig().AutoCommit {
+		if err := commitFiles(root, rels, "Move "+rels[0]+" to "+rels[1]); err != nil {
+			log.Print(err)
+		}
+	}
+	return result, nil
+}
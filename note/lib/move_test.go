@@ -0,0 +1,53 @@
+package lib
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMove(t *testing.T) {
+	dir, _ := createTestFiles()
+	defer os.RemoveAll(dir)
+	os.Setenv("NOTES_DIR", dir)
+	read := func(name string) string {
+		data, _ := ioutil.ReadFile(path.Join(dir, name))
+		return string(data)
+	}
+	ioutil.WriteFile(path.Join(dir, "kafka.md"), []byte("[[pulsar]] and [[pulsar|P]]\n[p](pulsar.md)\n"), 0644)
+	ioutil.WriteFile(path.Join(dir, "pulsar.md"), []byte("back to [kafka](kafka.md)\n"), 0644)
+
+	// Ambiguous and missing names are reported like Edit does.
+	_, err := Move("file", "foo", false)
+	_, ok := err.(*MultipleFilesError)
+	assert.True(t, ok)
+	_, err = Move("nosuchnote", "foo", false)
+	assert.Equal(t, NoFilesError(true), err)
+	_, err = Move("pulsar", "kafka", false)
+	assert.Equal(t, TargetExistsError(path.Join(dir, "kafka.md")), err)
+
+	// A dry run changes nothing.
+	result, err := Move("pulsar", "streams/pulsar-v2", true)
+	assert.Nil(t, err)
+	assert.Equal(t, path.Join(dir, "streams", "pulsar-v2.md"), result.To)
+	assert.Equal(t, []Rewrite{
+		{path.Join(dir, "kafka.md"), 1, "[[pulsar]] and [[pulsar|P]]", "[[pulsar-v2]] and [[pulsar-v2|P]]"},
+		{path.Join(dir, "kafka.md"), 2, "[p](pulsar.md)", "[p](streams/pulsar-v2.md)"},
+		{path.Join(dir, "streams", "pulsar-v2.md"), 1, "back to [kafka](kafka.md)", "back to [kafka](../kafka.md)"},
+	}, result.Rewrites)
+	assert.Equal(t, "back to [kafka](kafka.md)\n", read("pulsar.md"))
+
+	_, err = Move("pulsar", "streams/pulsar-v2", false)
+	assert.Nil(t, err)
+	_, err = os.Stat(path.Join(dir, "pulsar.md"))
+	assert.True(t, os.IsNotExist(err))
+	assert.Equal(t, "[[pulsar-v2]] and [[pulsar-v2|P]]\n[p](streams/pulsar-v2.md)\n", read("kafka.md"))
+	assert.Equal(t, "back to [kafka](../kafka.md)\n", read("streams/pulsar-v2.md"))
+
+	backlinks, err := Backlinks("pulsar-v2")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(backlinks))
+}
@@ -1,32 +1,40 @@
 package lib
 
 import (
-	"bufio"
-	"bytes"
 	"io/ioutil"
 	"log"
 	"os"
 	"os/exec"
 	"path"
 	"path/filepath"
-	"regexp"
 	"sort"
 	"strings"
+	"time"
 )
 
 type NoteDirNotSetError bool
 type EditorNotSetError bool
 type NoFilesError bool
+type InvalidSortError string
+type InvalidNameError string
 type MultipleFilesError struct {
 	files []string
 }
 
 func (e NoteDirNotSetError) Error() string {
-	return "'NOTES_DIR' environment variable not defined."
+	return "Notes directory not defined. Set 'NOTES_DIR' or 'notes_dir' in the config file."
 }
 
 func (e EditorNotSetError) Error() string {
-	return "'EDITOR' environment variable not defined."
+	return "Editor not defined. Set 'EDITOR' or 'editor' in the config file."
+}
+
+func (e InvalidSortError) Error() string {
+	return "Unknown sort order '" + string(e) + "'."
+}
+
+func (e InvalidNameError) Error() string {
+	return "'" + string(e) + "' is not a valid note name inside the notes directory."
 }
 
 func (e NoFilesError) Error() string {
@@ -35,11 +43,18 @@ func (e NoFilesError) Error() string {
 
 func (e *MultipleFilesError) Error() string {
 	errorMsg := "Multiple files match the name: \n"
-	errorMsg += strings.Join(BaseNames(e.files), "\n") + "\n"
+	errorMsg += strings.Join(RelNames(e.files), "\n") + "\n"
 	errorMsg += "Please choose a more exact one."
 	return errorMsg
 }
 
+// internalDirs hold the tool's own state rather than notes.
+var internalDirs = map[string]bool{
+	stateDir: true,
+	trashDir: true,
+	".git":   true,
+}
+
 type FileList []string
 
 func (f FileList) Len() int {
@@ -56,6 +71,87 @@ func (f FileList) Less(i, j int) bool {
 	return atimi < atimj
 }
 
+func Mtime(filename string) int64 {
+	fi, err := os.Stat(filename)
+	if err != nil {
+		return 0
+	}
+	return fi.ModTime().Unix()
+}
+
+// sortFiles sorts the files of the notebook rooted at root, the most
+// relevant one last.
+func sortFiles(root string, files []string, order string) error {
+	switch order {
+	case "", SortAtime:
+		sort.Sort(FileList(files))
+	case SortRecent, SortFrecency:
+		access := loadAccess(root)
+		now := time.Now()
+		last := make(map[string]time.Time)
+		score := make(map[string]float64)
+		for _, file := range files {
+			rel, _ := filepath.Rel(root, file)
+			a := access[filepath.ToSlash(rel)]
+			last[file] = a.lastUsed(file)
+			if order == SortFrecency {
+				score[file] = a.frecency(now)
+			}
+		}
+		sort.SliceStable(files, func(i, j int) bool {
+			if score[files[i]] != score[files[j]] {
+				return score[files[i]] < score[files[j]]
+			}
+			return last[files[i]].Before(last[files[j]])
+		})
+	case SortMtime:
+		sort.SliceStable(files, func(i, j int) bool {
+			return Mtime(files[i]) < Mtime(files[j])
+		})
+	case SortName:
+		sort.Strings(files)
+	case SortSize:
+		size := make(map[string]int64)
+		for _, file := range files {
+			if fi, err := os.Stat(file); err == nil {
+				size[file] = fi.Size()
+			}
+		}
+		sort.SliceStable(files, func(i, j int) bool {
+			return size[files[i]] < size[files[j]]
+		})
+	case SortCreated:
+		// Notes without a created date in their front matter count as
+		// created when last modified.
+		created := make(map[string]time.Time)
+		for _, file := range files {
+			if meta, err := ParseMeta(file); err == nil && !meta.Created.IsZero() {
+				created[file] = meta.Created
+			} else {
+				created[file] = time.Unix(Mtime(file), 0)
+			}
+		}
+		sort.SliceStable(files, func(i, j int) bool {
+			return created[files[i]].Before(created[files[j]])
+		})
+	default:
+		return InvalidSortError(order)
+	}
+	return nil
+}
+
+func ignored(rel string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, rel); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, filepath.Base(rel)); ok {
+			return true
+		}
+	}
+	return false
+}
+
 func BaseNames(files []string) []string {
 	var basenames []string
 	for _, file := range files {
@@ -64,93 +160,282 @@ func BaseNames(files []string) []string {
 	return basenames
 }
 
-func List(name string) ([]string, error) {
-	dir := os.Getenv("NOTES_DIR")
-	if dir == "" {
-		return nil, NoteDirNotSetError(true)
+// relPath returns the path of file relative to root, and false when file
+// isn't inside root.
+func relPath(root, file string) (string, bool) {
+	rel, err := filepath.Rel(root, file)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
+}
+
+// RelName returns the name of a note relative to the root of its notebook,
+// like "work/plan.md". Files outside every notebook keep their path.
+func RelName(file string) string {
+	root, err := rootOf(file)
+	if err != nil {
+		return file
+	}
+	rel, ok := relPath(root, file)
+	if !ok {
+		return file
+	}
+	return filepath.ToSlash(rel)
+}
+
+func RelNames(files []string) []string {
+	var names []string
+	for _, file := range files {
+		names = append(names, RelName(file))
 	}
-	var files []string
-	walkFn := func(path string, info os.FileInfo, err error) error {
+	return names
+}
+
+// notePath returns the file of the note or folder name in the notebook
+// rooted at root. Names may contain folders, as in "work/plan.md", but must
+// stay inside the notebook and out of the tool's own directories.
+func notePath(root, name string) (string, error) {
+	file := filepath.Join(root, filepath.FromSlash(name))
+	rel, ok := relPath(root, file)
+	if !ok || rel == "." || internalDirs[strings.Split(rel, string(filepath.Separator))[0]] {
+		return "", InvalidNameError(name)
+	}
+	return file, nil
+}
+
+// walkNotes calls fn for every note and folder below root, leaving out
+// ignored paths and the tool's own directories.
+func walkNotes(root string, ignore []string, fn func(path string, info os.FileInfo)) {
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			log.Print(err)
 			return nil
 		}
-		if info.IsDir() {
+		if path == root {
 			return nil
 		}
-		if name == "" || strings.Contains(path, name) {
-			files = append(files, path)
+		if rel, err := filepath.Rel(root, path); err == nil && ignored(rel, ignore) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() && internalDirs[info.Name()] {
+			return filepath.SkipDir
 		}
+		fn(path, info)
 		return nil
-	}
-	filepath.Walk(dir, walkFn)
-	sort.Sort(FileList(files))
-	return files, nil
+	})
+}
+
+// List returns the notes whose path relative to the notebook root contains
+// name, so "work/" lists the notes of the work folder. When none does, the
+// notes fuzzily matching name are returned instead.
+func List(name string) ([]string, error) {
+	return list(name, true)
 }
 
-func Grep(pattern string) ([]string, error) {
-	files, err := List("")
+func list(name string, fuzzy bool) ([]string, error) {
+	conf := GetConfig()
+	dir, err := conf.Root()
 	if err != nil {
 		return nil, err
 	}
-	patternBytes := []byte(pattern)
-	var matchingFiles []string
-	for _, file := range files {
-		f, err := os.Open(file)
-		if err != nil {
-			log.Print(err)
-			continue
-		}
-		defer f.Close()
-		scanner := bufio.NewScanner(f)
-		for scanner.Scan() {
-			if bytes.Contains(scanner.Bytes(), patternBytes) {
-				matchingFiles = append(matchingFiles, file)
-				break
+	// Absolute paths of notes work as names too.
+	if filepath.IsAbs(name) {
+		if rel, ok := relPath(dir, name); ok {
+			name = rel
+		}
+	}
+	name = filepath.ToSlash(name)
+	var files, all []string
+	if resp := fromDaemon(daemonRequest{Op: "files"}); resp != nil {
+		all = resp.Files
+	} else {
+		walkNotes(dir, conf.Ignore, func(path string, info os.FileInfo) {
+			if !info.IsDir() {
+				all = append(all, path)
 			}
+		})
+	}
+	for _, path := range all {
+		rel, _ := filepath.Rel(dir, path)
+		if name == "" || strings.Contains(filepath.ToSlash(rel), name) {
+			files = append(files, path)
 		}
 	}
-	return matchingFiles, nil
+	fuzzy = fuzzy && name != "" && len(files) == 0
+	if fuzzy {
+		files = all
+	}
+	if err := sortFiles(dir, files, conf.Sort); err != nil {
+		return nil, err
+	}
+	if fuzzy {
+		return fuzzyMatch(dir, name, files), nil
+	}
+	return files, nil
+}
+
+type EditOptions struct {
+	// Create creates the note when no note matches the name.
+	Create bool
+	// Encrypt encrypts the note, turning a plain text note into one
+	// ending in .age.
+	Encrypt bool
+	// Template is the template new notes are created from, instead of the
+	// default template of their directory. Vars and Prompt provide the
+	// values of custom template variables.
+	Template string
+	Vars     map[string]string
+	Prompt   func(name string) (string, error)
+}
+
+func Edit(name string, create bool) error {
+	return EditWith(name, EditOptions{Create: create})
 }
 
-func Clean() error {
-	files, err := List("")
+func EditWith(name string, opts EditOptions) error {
+	conf := GetConfig()
+	editor := strings.Fields(conf.Editor)
+	if len(editor) == 0 {
+		return EditorNotSetError(true)
+	}
+	// A name meant for a new note shouldn't fuzzily open another one.
+	matchingFiles, err := list(name, !opts.Create)
 	if err != nil {
 		return err
 	}
-	tempFileRegex, _ := regexp.Compile(".*(swp|swo|swn)")
-	for _, name := range files {
-		if tempFileRegex.MatchString(path.Base(name)) {
-			os.Remove(name)
-		}
+	return editMatching(editor, matchingFiles, name, opts)
+}
+
+// Resolve returns the only note whose name contains name. Notes only
+// matching fuzzily don't count, so commands changing or removing a note
+// never pick an unrelated one.
+func Resolve(name string) (string, error) {
+	return resolve(name, false)
+}
+
+// resolve is like Resolve, but with fuzzy also falls back to the notes
+// fuzzily matching name, for commands only reading the note.
+func resolve(name string, fuzzy bool) (string, error) {
+	matchingFiles, err := list(name, fuzzy)
+	if err != nil {
+		return "", err
 	}
-	return nil
+	return single(matchingFiles)
 }
 
-func Edit(name string, create bool) error {
-	editor := os.Getenv("EDITOR")
-	if editor == "" {
-		return EditorNotSetError(true)
+// Read returns the note matching name and its plain text, decrypting it
+// when needed. Reading a note counts as opening it.
+func Read(name string) (string, []byte, error) {
+	file, err := resolve(name, true)
+	if err != nil {
+		return "", nil, err
+	}
+	var k *keys
+	if IsEncrypted(file) {
+		if k, err = loadKeys(); err != nil {
+			return "", nil, err
+		}
 	}
-	matchingFiles, err := List(name)
+	data, err := readNote(file, k)
 	if err != nil {
-		return err
+		return "", nil, err
 	}
-	var file string
+	if err := recordAccess(file); err != nil {
+		log.Print(err)
+	}
+	return file, data, nil
+}
+
+func single(matchingFiles []string) (string, error) {
 	if len(matchingFiles) == 0 {
-		if !create {
-			return NoFilesError(true)
-		}
-		file = path.Join(os.Getenv("NOTES_DIR"), name)
-		ioutil.WriteFile(file, []byte(""), 0644)
+		return "", NoFilesError(true)
 	} else if len(matchingFiles) > 1 {
-		return &MultipleFilesError{matchingFiles}
+		if choose := GetConfig().Choose; choose != nil {
+			return choose(matchingFiles)
+		}
+		return "", &MultipleFilesError{matchingFiles}
+	}
+	return matchingFiles[0], nil
+}
+
+func editMatching(editor []string, matchingFiles []string, name string, opts EditOptions) error {
+	file, err := single(matchingFiles)
+	if _, ok := err.(NoFilesError); ok && opts.Create {
+		dir, err := GetConfig().Root()
+		if err != nil {
+			return err
+		}
+		if file, err = notePath(dir, name); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			return err
+		}
+		if opts.Encrypt && !IsEncrypted(file) {
+			file += encryptedExts[0]
+		}
+		if err := createNote(file, name, opts); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+	if opts.Encrypt && !IsEncrypted(file) {
+		if file, err = encryptCommitted(file); err != nil {
+			return err
+		}
+	}
+	if IsEncrypted(file) {
+		err = editEncrypted(editor, file)
 	} else {
-		file = matchingFiles[0]
+		err = runEditor(editor, file)
+	}
+	if err != nil {
+		return err
+	}
+	if err := recordAccess(file); err != nil {
+		log.Print(err)
 	}
-	cmd := exec.Command(editor, file)
+	if GetConfig().AutoCommit {
+		if err := commitNote(file, "Edit %s"); err != nil {
+			log.Print(err)
+		}
+	}
+	return nil
+}
+
+// createNote writes a new note, from a template when one is given or
+// configured for the directory of the note.
+func createNote(file, name string, opts EditOptions) error {
+	tmpl := opts.Template
+	if tmpl == "" {
+		tmpl = defaultTemplate(name)
+	}
+	var content []byte
+	if tmpl != "" {
+		var err error
+		content, err = RenderTemplate(tmpl, name, opts.Vars, opts.Prompt)
+		if err != nil {
+			return err
+		}
+	}
+	if IsEncrypted(file) && len(content) > 0 {
+		k, err := loadKeys()
+		if err != nil {
+			return err
+		}
+		return k.encrypt(file, content)
+	}
+	return ioutil.WriteFile(file, content, 0644)
+}
+
+func runEditor(editor []string, file string) error {
+	cmd := exec.Command(editor[0], append(editor[1:], file)...)
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
-	err = cmd.Run()
-	return err
+	return cmd.Run()
 }
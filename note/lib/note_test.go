@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 	"path"
+	"sort"
 	"strconv"
 	"testing"
 	"time"
@@ -95,11 +96,23 @@ func TestClean(t *testing.T) {
 				extension = "swn"
 			}
 			oldFileName := fileName
-			fileName = fileName + extension
+			fileName = path.Join(dir, "."+path.Base(fileName)+"."+extension)
 			os.Rename(oldFileName, fileName)
 			removedFiles = append(removedFiles, fileName)
 		}
 	}
+	keptFile := path.Join(dir, "answp.md")
+	ioutil.WriteFile(keptFile, []byte(""), 0644)
+
+	// A dry run removes nothing.
+	result, err := CleanWith(true)
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, removedFiles, result.Removed)
+	for _, removedFile := range removedFiles {
+		_, err = os.Stat(removedFile)
+		assert.Nil(t, err)
+	}
+
 	err = Clean()
 	assert.Nil(t, err)
 	for _, removedFile := range removedFiles {
@@ -107,6 +120,61 @@ func TestClean(t *testing.T) {
 		errorMsg := removedFile + " exists"
 		assert.True(t, os.IsNotExist(err), errorMsg)
 	}
+	_, err = os.Stat(keptFile)
+	assert.Nil(t, err)
+}
+
+func TestCleanRules(t *testing.T) {
+	dir, _ := createTestFiles()
+	defer os.RemoveAll(dir)
+	SetConfig(&Config{NotesDir: dir, CleanRules: DefaultCleanRules})
+	defer SetConfig(nil)
+	for _, name := range []string{"#foo#", "foo~", ".DS_Store", "desktop.ini", "foo.md", "foo.bak"} {
+		ioutil.WriteFile(path.Join(dir, name), []byte("data"), 0644)
+	}
+	result, err := CleanWith(false)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(result.Removed))
+	assert.Equal(t, int64(16), result.Size)
+
+	// Backups are only removed when asked for.
+	_, err = os.Stat(path.Join(dir, "foo.bak"))
+	assert.Nil(t, err)
+	GetConfig().CleanPatterns = []string{`\.bak$`}
+	result, err = CleanWith(false)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{path.Join(dir, "foo.bak")}, result.Removed)
+	GetConfig().CleanPatterns = nil
+
+	// Age and size conditions.
+	old := time.Now().Add(-48 * time.Hour)
+	for _, name := range []string{"old.log", "new.log", "big.log"} {
+		ioutil.WriteFile(path.Join(dir, name), []byte(name), 0644)
+	}
+	ioutil.WriteFile(path.Join(dir, "big.log"), make([]byte, 100), 0644)
+	os.Chtimes(path.Join(dir, "old.log"), old, old)
+	os.Chtimes(path.Join(dir, "big.log"), old, old)
+	GetConfig().CleanRules = []CleanRule{{Glob: "*.log", MinAge: "1d", MaxSize: 50}}
+	result, err = CleanWith(false)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{path.Join(dir, "old.log")}, result.Removed)
+
+	// Invalid rules are reported.
+	GetConfig().CleanRules = []CleanRule{{Regex: "("}}
+	_, err = CleanWith(false)
+	_, ok := err.(*InvalidCleanRuleError)
+	assert.True(t, ok)
+
+	// Removal errors are reported.
+	GetConfig().CleanRules = []CleanRule{{Glob: "*.md"}}
+	os.Chmod(dir, 0555)
+	defer os.Chmod(dir, 0755)
+	result, err = CleanWith(false)
+	if os.Geteuid() != 0 {
+		_, ok = err.(CleanError)
+		assert.True(t, ok)
+		assert.Equal(t, 1, len(result.Failures))
+	}
 }
 
 func TestGrep(t *testing.T) {
@@ -128,8 +196,8 @@ func TestGrep(t *testing.T) {
 		}
 	}
 	setAtime(expectedFileNames)
-	returnedFileNames, _ := Grep("foo is bar")
-	assert.Equal(t, expectedFileNames, returnedFileNames)
+	matches, _ := Grep("foo is bar", GrepOptions{})
+	assert.Equal(t, expectedFileNames, MatchedFiles(matches))
 }
 
 func TestEditor(t *testing.T) {
@@ -188,3 +256,76 @@ func TestEditor(t *testing.T) {
 	err = Edit("foo1", false)
 	assert.NotNil(t, err)
 }
+
+func TestListConfig(t *testing.T) {
+	dir, files := createTestFiles()
+	defer os.RemoveAll(dir)
+	SetConfig(&Config{NotesDir: dir, Sort: SortName, Ignore: []string{"*.swp"}})
+	defer SetConfig(nil)
+
+	// Ignored files are not listed and names are sorted.
+	ioutil.WriteFile(path.Join(dir, "a.swp"), []byte(""), 0644)
+	returnedFileNames, err := List("")
+	assert.Nil(t, err)
+	expectedFileNames := append([]string{}, files...)
+	sort.Strings(expectedFileNames)
+	assert.Equal(t, expectedFileNames, returnedFileNames)
+
+	// Unknown sort orders are rejected.
+	SetConfig(&Config{NotesDir: dir, Sort: "foo"})
+	_, err = List("")
+	assert.Equal(t, InvalidSortError("foo"), err)
+}
+
+func TestSubdirectories(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "note")
+	defer os.RemoveAll(dir)
+	SetConfig(&Config{NotesDir: dir, Editor: "true", Sort: SortName})
+	defer SetConfig(nil)
+
+	// Creating a note in a folder creates the folder.
+	assert.Nil(t, Edit("work/plan.md", true))
+	assert.Nil(t, Edit("home/plan.md", true))
+	work := path.Join(dir, "work", "plan.md")
+	home := path.Join(dir, "home", "plan.md")
+	_, err := os.Stat(work)
+	assert.Nil(t, err)
+
+	// Names are relative to the root and tell notes with the same base
+	// name apart.
+	returnedFileNames, _ := List("plan")
+	assert.Equal(t, []string{home, work}, returnedFileNames)
+	assert.Equal(t, []string{"home/plan.md", "work/plan.md"}, RelNames(returnedFileNames))
+	returnedFileNames, _ = List("work/")
+	assert.Equal(t, []string{work}, returnedFileNames)
+	returnedFileNames, _ = List(work)
+	assert.Equal(t, []string{work}, returnedFileNames)
+	_, err = Resolve("plan")
+	assert.Contains(t, err.Error(), "work/plan.md")
+
+	// The notes directory itself isn't part of the names.
+	returnedFileNames, _ = List(path.Base(dir))
+	assert.Nil(t, returnedFileNames)
+
+	// Names can't leave the notes directory.
+	assert.Equal(t, InvalidNameError("../plan.md"), Edit("../plan.md", true))
+	assert.Equal(t, InvalidNameError(".git/plan.md"), Edit(".git/plan.md", true))
+}
+
+func TestRead(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "note")
+	defer os.RemoveAll(dir)
+	SetConfig(&Config{NotesDir: dir})
+	defer SetConfig(nil)
+	ioutil.WriteFile(path.Join(dir, "plan.md"), []byte("# Plan\n"), 0644)
+
+	file, data, err := Read("plan")
+	assert.Nil(t, err)
+	assert.Equal(t, path.Join(dir, "plan.md"), file)
+	assert.Equal(t, "# Plan\n", string(data))
+	recent, _ := Recent()
+	assert.Equal(t, 1, len(recent))
+
+	_, _, err = Read("missing")
+	assert.Equal(t, NoFilesError(true), err)
+}
@@ -0,0 +1,138 @@
+package lib
+
+import (
+	"context"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type NotebookNotFoundError string
+
+func (e NotebookNotFoundError) Error() string {
+	return "No notebook named '" + string(e) + "'."
+}
+
+// NotebookFile is a file found in one of the configured notebooks.
+type NotebookFile struct {
+	Notebook string
+	File     string
+}
+
+func (f NotebookFile) String() string {
+	rel, ok := relPath(GetConfig().Notebooks[f.Notebook], f.File)
+	if !ok {
+		return f.Notebook + ":" + path.Base(f.File)
+	}
+	return f.Notebook + ":" + filepath.ToSlash(rel)
+}
+
+// NotebookOf returns the name of the configured notebook holding file, the
+// innermost one when notebooks are nested, and false when none does.
+func NotebookOf(file string) (string, bool) {
+	conf := GetConfig()
+	best := ""
+	for name, root := range conf.Notebooks {
+		if _, ok := relPath(root, file); ok && (best == "" || len(root) > len(conf.Notebooks[best])) {
+			best = name
+		}
+	}
+	return best, best != ""
+}
+
+func NotebookNames() []string {
+	var names []string
+	for name := range GetConfig().Notebooks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// eachNotebook calls fn once for every configured notebook, with the config
+// switched to that notebook for the duration of the call.
+func eachNotebook(fn func(notebook string) error) error {
+	saved := config
+	defer SetConfig(saved)
+	conf := GetConfig()
+	names := NotebookNames()
+	if len(names) == 0 {
+		return NoteDirNotSetError(true)
+	}
+	for _, name := range names {
+		nbConf := *conf
+		nbConf.Notebook = name
+		SetConfig(&nbConf)
+		if err := fn(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func ListAll(name string, filter Filter) ([]NotebookFile, error) {
+	return listAll(name, filter, true)
+}
+
+func listAll(name string, filter Filter, fuzzy bool) ([]NotebookFile, error) {
+	var files []NotebookFile
+	err := eachNotebook(func(notebook string) error {
+		nbFiles, err := listWith(name, filter, fuzzy)
+		for _, file := range nbFiles {
+			files = append(files, NotebookFile{notebook, file})
+		}
+		return err
+	})
+	return files, err
+}
+
+func GrepAll(pattern string, opts GrepOptions) ([]Match, error) {
+	var matches []Match
+	err := GrepAllContext(context.Background(), pattern, opts, func(match Match) error {
+		matches = append(matches, match)
+		return nil
+	})
+	return matches, err
+}
+
+// GrepAllContext is like GrepContext but searches every notebook. MaxCount
+// limits the matching lines of all notebooks together.
+func GrepAllContext(ctx context.Context, pattern string, opts GrepOptions, fn func(Match) error) error {
+	count := 0
+	return eachNotebook(func(notebook string) error {
+		nbOpts := opts
+		if opts.MaxCount > 0 {
+			if count >= opts.MaxCount {
+				return nil
+			}
+			nbOpts.MaxCount -= count
+		}
+		return GrepContext(ctx, pattern, nbOpts, func(match Match) error {
+			if !match.Context {
+				count++
+			}
+			match.Notebook = notebook
+			return fn(match)
+		})
+	})
+}
+
+// EditAll is like Edit but looks for the note in every notebook. New notes
+// are still created in the selected notebook.
+func EditAll(name string, opts EditOptions) error {
+	editor := strings.Fields(GetConfig().Editor)
+	if len(editor) == 0 {
+		return EditorNotSetError(true)
+	}
+	// A name meant for a new note shouldn't fuzzily open another one.
+	notebookFiles, err := listAll(name, Filter{}, !opts.Create)
+	if err != nil {
+		return err
+	}
+	var matchingFiles []string
+	for _, notebookFile := range notebookFiles {
+		matchingFiles = append(matchingFiles, notebookFile.File)
+	}
+	return editMatching(editor, matchingFiles, name, opts)
+}
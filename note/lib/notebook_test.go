@@ -0,0 +1,63 @@
+package lib
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotebooks(t *testing.T) {
+	workDir, workFiles := createTestFiles()
+	defer os.RemoveAll(workDir)
+	homeDir, homeFiles := createTestFiles()
+	defer os.RemoveAll(homeDir)
+	SetConfig(&Config{
+		Notebook:  "work",
+		Notebooks: map[string]string{"work": workDir, "home": homeDir},
+	})
+	defer SetConfig(nil)
+
+	// Only the selected notebook is listed.
+	returnedFileNames, err := List("")
+	assert.Nil(t, err)
+	assert.Equal(t, workFiles, returnedFileNames)
+
+	// All notebooks are listed in name order.
+	var expectedFiles []NotebookFile
+	for _, file := range homeFiles {
+		expectedFiles = append(expectedFiles, NotebookFile{"home", file})
+	}
+	for _, file := range workFiles {
+		expectedFiles = append(expectedFiles, NotebookFile{"work", file})
+	}
+	returnedFiles, err := ListAll("", Filter{})
+	assert.Nil(t, err)
+	assert.Equal(t, expectedFiles, returnedFiles)
+	assert.Equal(t, "work", GetConfig().Notebook)
+
+	// Grep across notebooks.
+	ioutil.WriteFile(homeFiles[2], []byte("foo is bar"), 0644)
+	matches, err := GrepAll("foo is bar", GrepOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, []Match{{"home", homeFiles[2], 1, 1, "foo is bar", false}}, matches)
+	assert.Equal(t, "home:"+path.Base(homeFiles[2]), NotebookFile{"home", homeFiles[2]}.String())
+
+	// Notes belong to the notebook they are in, whichever is selected,
+	// also when their name starts with "..".
+	root, err := rootOf(path.Join(homeDir, "..notes.md"))
+	assert.Nil(t, err)
+	assert.Equal(t, homeDir, root)
+	notebook, ok := NotebookOf(path.Join(homeDir, "..notes.md"))
+	assert.True(t, ok)
+	assert.Equal(t, "home", notebook)
+	_, ok = NotebookOf(path.Join(homeDir, "..", "notes.md"))
+	assert.False(t, ok)
+
+	// Unknown notebooks are reported.
+	SetConfig(&Config{Notebook: "foo"})
+	_, err = List("")
+	assert.Equal(t, NotebookNotFoundError("foo"), err)
+}
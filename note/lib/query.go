@@ -0,0 +1,484 @@
+package lib
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// QuerySyntaxError is a mistake in a query given to Find. Pos is the byte
+// offset in Query where it was found.
+type QuerySyntaxError struct {
+	Query string
+	Pos   int
+	Msg   string
+}
+
+func (e *QuerySyntaxError) Error() string {
+	marker := strings.Repeat(" ", utf8.RuneCountInString(e.Query[:e.Pos])) + "^"
+	return fmt.Sprintf("%s at position %d:\n%s\n%s", e.Msg, e.Pos+1, e.Query, marker)
+}
+
+type tokenKind int
+
+const (
+	tokWord tokenKind = iota
+	tokLParen
+	tokRParen
+	tokEOF
+)
+
+// token is a word of a query. Words like field:value have field set and
+// value holds the word, or the part after the colon, without quotes.
+type token struct {
+	kind   tokenKind
+	pos    int
+	field  string
+	value  string
+	quoted bool
+}
+
+// keyword reports whether t is one of the operators AND, OR and NOT.
+func (t token) keyword(word string) bool {
+	return t.kind == tokWord && !t.quoted && t.field == "" && t.value == word
+}
+
+var fieldNameRegex = regexp.MustCompile(`^[a-z]+$`)
+
+func lexQuery(query string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(query) {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+			continue
+		case c == '(':
+			tokens = append(tokens, token{kind: tokLParen, pos: i})
+			i++
+			continue
+		case c == ')':
+			tokens = append(tokens, token{kind: tokRParen, pos: i})
+			i++
+			continue
+		case c == '-' && i+1 < len(query) && !strings.ContainsRune(" \t\n)", rune(query[i+1])):
+			// -word is short for NOT word.
+			tokens = append(tokens, token{kind: tokWord, pos: i, value: "NOT"})
+			i++
+			continue
+		}
+		t := token{kind: tokWord, pos: i}
+		var b strings.Builder
+		for i < len(query) && !strings.ContainsRune(" \t\n()", rune(query[i])) {
+			c := query[i]
+			if c == '"' {
+				end := strings.IndexByte(query[i+1:], '"')
+				if end < 0 {
+					return nil, &QuerySyntaxError{query, i, "Unterminated quote"}
+				}
+				b.WriteString(query[i+1 : i+1+end])
+				i += end + 2
+				t.quoted = true
+				continue
+			}
+			if c == ':' && t.field == "" && !t.quoted && fieldNameRegex.MatchString(b.String()) {
+				t.field = b.String()
+				b.Reset()
+				i++
+				continue
+			}
+			b.WriteByte(c)
+			i++
+		}
+		t.value = b.String()
+		tokens = append(tokens, t)
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(query)}), nil
+}
+
+// queryNote is a note a query is evaluated on. Its metadata and content
+// are only read when a term needs them.
+type queryNote struct {
+	file    string
+	rel     string
+	k       *keys
+	info    os.FileInfo
+	meta    *Meta
+	content []byte
+}
+
+func (n *queryNote) stat() os.FileInfo {
+	if n.info == nil {
+		fi, err := os.Stat(n.file)
+		if err != nil {
+			return nil
+		}
+		n.info = fi
+	}
+	return n.info
+}
+
+func (n *queryNote) text() []byte {
+	if n.content == nil {
+		n.content = []byte{}
+		if IsEncrypted(n.file) && len(n.k.identities) == 0 {
+			return n.content
+		}
+		f, err := openNote(n.file, n.k)
+		if err != nil {
+			return n.content
+		}
+		defer f.Close()
+		if data, err := ioutil.ReadAll(f); err == nil {
+			n.content = data
+		}
+	}
+	return n.content
+}
+
+func (n *queryNote) metadata() *Meta {
+	if n.meta == nil {
+		n.meta = &Meta{}
+		if meta, err := readMeta(bytes.NewReader(n.text())); err == nil {
+			n.meta = meta
+		}
+	}
+	return n.meta
+}
+
+type queryNode interface {
+	eval(n *queryNote) bool
+}
+
+type andNode struct{ left, right queryNode }
+type orNode struct{ left, right queryNode }
+type notNode struct{ node queryNode }
+
+func (q andNode) eval(n *queryNote) bool { return q.left.eval(n) && q.right.eval(n) }
+func (q orNode) eval(n *queryNote) bool  { return q.left.eval(n) || q.right.eval(n) }
+func (q notNode) eval(n *queryNote) bool { return !q.node.eval(n) }
+
+// contentTerm matches notes containing a string, ignoring case like
+// "note grep -i". Find fills in files, the notes grep found it in.
+type contentTerm struct {
+	value string
+	files map[string]bool
+}
+
+func (q *contentTerm) eval(n *queryNote) bool { return q.files[n.file] }
+
+// contentTerms returns the content terms of the query rooted at node.
+func contentTerms(node queryNode) []*contentTerm {
+	switch q := node.(type) {
+	case andNode:
+		return append(contentTerms(q.left), contentTerms(q.right)...)
+	case orNode:
+		return append(contentTerms(q.left), contentTerms(q.right)...)
+	case notNode:
+		return contentTerms(q.node)
+	case *contentTerm:
+		return []*contentTerm{q}
+	}
+	return nil
+}
+
+type pathTerm struct{ value string }
+
+func (q pathTerm) eval(n *queryNote) bool {
+	return strings.Contains(strings.ToLower(n.rel), strings.ToLower(q.value))
+}
+
+type tagTerm struct{ tag string }
+
+func (q tagTerm) eval(n *queryNote) bool { return n.metadata().HasTag(q.tag) }
+
+// titleTerm matches the title of the front matter, or the name of notes
+// without one.
+type titleTerm struct{ value string }
+
+func (q titleTerm) eval(n *queryNote) bool {
+	title := n.metadata().Title
+	if title == "" {
+		title = withoutExt(filepath.Base(n.file))
+	}
+	return strings.Contains(strings.ToLower(title), strings.ToLower(q.value))
+}
+
+// dateTerm compares the day a note was created or modified with another
+// one, from is the start of that day and to the start of the next one.
+type dateTerm struct {
+	field string
+	op    string
+	from  time.Time
+	to    time.Time
+}
+
+func (q dateTerm) eval(n *queryNote) bool {
+	var t time.Time
+	if q.field == "created" {
+		t = n.metadata().Created
+	}
+	if t.IsZero() {
+		fi := n.stat()
+		if fi == nil {
+			return false
+		}
+		t = fi.ModTime()
+	}
+	switch q.op {
+	case ">":
+		return !t.Before(q.to)
+	case ">=":
+		return !t.Before(q.from)
+	case "<":
+		return t.Before(q.from)
+	case "<=":
+		return t.Before(q.to)
+	}
+	return !t.Before(q.from) && t.Before(q.to)
+}
+
+type sizeTerm struct {
+	op   string
+	size int64
+}
+
+func (q sizeTerm) eval(n *queryNote) bool {
+	fi := n.stat()
+	if fi == nil {
+		return false
+	}
+	switch q.op {
+	case ">":
+		return fi.Size() > q.size
+	case ">=":
+		return fi.Size() >= q.size
+	case "<":
+		return fi.Size() < q.size
+	case "<=":
+		return fi.Size() <= q.size
+	}
+	return fi.Size() == q.size
+}
+
+var sizeRegex = regexp.MustCompile(`^(?i)(\d+)([kmg]?)b?$`)
+
+func parseSize(s string) (int64, bool) {
+	m := sizeRegex.FindStringSubmatch(s)
+	if m == nil {
+		return 0, false
+	}
+	size, _ := strconv.ParseInt(m[1], 10, 64)
+	switch strings.ToLower(m[2]) {
+	case "k":
+		size <<= 10
+	case "m":
+		size <<= 20
+	case "g":
+		size <<= 30
+	}
+	return size, true
+}
+
+type queryParser struct {
+	query  string
+	tokens []token
+	pos    int
+	now    time.Time
+}
+
+func (p *queryParser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *queryParser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *queryParser) errorAt(pos int, format string, args ...interface{}) error {
+	return &QuerySyntaxError{p.query, pos, fmt.Sprintf(format, args...)}
+}
+
+func (p *queryParser) parseOr() (queryNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().keyword("OR") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+// parseAnd parses terms joined by AND, which may be left out.
+func (p *queryParser) parseAnd() (queryNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if t.keyword("AND") {
+			p.next()
+		} else if t.kind == tokEOF || t.kind == tokRParen || t.keyword("OR") {
+			return left, nil
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+}
+
+func (p *queryParser) parseNot() (queryNode, error) {
+	if p.peek().keyword("NOT") {
+		p.next()
+		node, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{node}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *queryParser) parsePrimary() (queryNode, error) {
+	t := p.next()
+	switch {
+	case t.kind == tokLParen:
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if end := p.next(); end.kind != tokRParen {
+			return nil, p.errorAt(end.pos, "Expected ')'")
+		}
+		return node, nil
+	case t.kind == tokRParen:
+		return nil, p.errorAt(t.pos, "Unexpected ')'")
+	case t.kind == tokEOF:
+		return nil, p.errorAt(t.pos, "Unexpected end of query")
+	case t.keyword("AND") || t.keyword("OR"):
+		return nil, p.errorAt(t.pos, "Unexpected %s", t.value)
+	}
+	return p.parseTerm(t)
+}
+
+var comparisonRegex = regexp.MustCompile(`^(>=|<=|>|<|=)?(.*)$`)
+
+func (p *queryParser) parseTerm(t token) (queryNode, error) {
+	valuePos := t.pos
+	if t.field != "" {
+		valuePos += len(t.field) + 1
+	}
+	if t.value == "" {
+		return nil, p.errorAt(valuePos, "Missing value")
+	}
+	switch t.field {
+	case "", "content", "text":
+		return &contentTerm{value: t.value}, nil
+	case "path", "name":
+		return pathTerm{t.value}, nil
+	case "tag", "tags":
+		return tagTerm{strings.TrimPrefix(t.value, "#")}, nil
+	case "title":
+		return titleTerm{t.value}, nil
+	case "created", "modified":
+		m := comparisonRegex.FindStringSubmatch(t.value)
+		date, err := ParseDate(m[2], p.now)
+		if err != nil || m[2] == "" {
+			return nil, p.errorAt(valuePos+len(m[1]), "Can't understand the date '%s'", m[2])
+		}
+		return dateTerm{t.field, m[1], date, date.AddDate(0, 0, 1)}, nil
+	case "size":
+		m := comparisonRegex.FindStringSubmatch(t.value)
+		size, ok := parseSize(m[2])
+		if !ok {
+			return nil, p.errorAt(valuePos+len(m[1]), "Can't understand the size '%s'", m[2])
+		}
+		return sizeTerm{m[1], size}, nil
+	}
+	return nil, p.errorAt(t.pos, "Unknown field '%s'", t.field)
+}
+
+func parseQuery(query string, now time.Time) (queryNode, error) {
+	tokens, err := lexQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	p := &queryParser{query: query, tokens: tokens, now: now}
+	if p.peek().kind == tokEOF {
+		return nil, p.errorAt(0, "Empty query")
+	}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, p.errorAt(t.pos, "Unexpected ')'")
+	}
+	return node, nil
+}
+
+// Find returns the notes of the selected notebook matching query, in the
+// order of List. Words of a query match the content of notes, ignoring
+// case, and field:value terms their path, tags, title, created and
+// modified dates or size, e.g.
+//
+//	tag:work AND (kafka OR pulsar) NOT draft modified:>2024-01-01 title:"retro"
+//
+// Terms next to each other must all match, NOT or a leading - negates a
+// term and dates and sizes can be compared with >, >=, < and <=. Content
+// terms are searched for like Grep does, using the search index.
+func Find(query string) ([]string, error) {
+	node, err := parseQuery(query, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	for _, term := range contentTerms(node) {
+		term.files = make(map[string]bool)
+		err := GrepContext(context.Background(), term.value, GrepOptions{IgnoreCase: true}, func(match Match) error {
+			term.files[match.File] = true
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	files, err := List("")
+	if err != nil {
+		return nil, err
+	}
+	root, _ := GetConfig().Root()
+	k, err := loadKeys()
+	if err != nil {
+		return nil, err
+	}
+	var matching []string
+	for _, file := range files {
+		rel, _ := filepath.Rel(root, file)
+		if node.eval(&queryNote{file: file, rel: rel, k: k}) {
+			matching = append(matching, file)
+		}
+	}
+	return matching, nil
+}
@@ -0,0 +1,83 @@
+package lib
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFind(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "note")
+	defer os.RemoveAll(dir)
+	SetConfig(&Config{NotesDir: dir, Sort: SortName})
+	defer SetConfig(nil)
+	os.Mkdir(path.Join(dir, "work"), 0755)
+	notes := map[string]string{
+		"work/kafka.md":  "---\ntitle: Kafka retro\ntags: [work]\n---\nWe moved to Kafka.\n",
+		"work/pulsar.md": "---\ntitle: Pulsar retro\ntags: [work, draft]\n---\nPulsar it is. DRAFT\n",
+		"home.md":        "---\ntags: [home]\ncreated: 2023-05-01\n---\nkafka at home\n",
+	}
+	for name, content := range notes {
+		ioutil.WriteFile(path.Join(dir, name), []byte(content), 0644)
+	}
+	old := time.Date(2023, time.June, 1, 12, 0, 0, 0, time.Local)
+	os.Chtimes(path.Join(dir, "home.md"), old, old)
+
+	for query, expected := range map[string][]string{
+		`tag:work AND (kafka OR pulsar) NOT draft modified:>2024-01-01 title:"retro"`: {"work/kafka.md"},
+		`kafka`:                     {"home.md", "work/kafka.md"},
+		`kafka OR pulsar`:           {"home.md", "work/kafka.md", "work/pulsar.md"},
+		`pulsar OR kafka -tag:home`: {"work/kafka.md", "work/pulsar.md"},
+		`path:work -draft`:          {"work/kafka.md"},
+		`title:"pulsar retro"`:      {"work/pulsar.md"},
+		`title:home`:                {"home.md"},
+		`created:2023-05-01`:        {"home.md"},
+		`modified:<2024-01-01`:      {"home.md"},
+		`size:>60 tag:work`:         {"work/pulsar.md"},
+		`"it is."`:                  {"work/pulsar.md"},
+	} {
+		files, err := Find(query)
+		assert.Nil(t, err, query)
+		var rels []string
+		for _, file := range files {
+			rels = append(rels, file[len(dir)+1:])
+		}
+		assert.Equal(t, expected, rels, query)
+	}
+
+	// Content terms go through the search index, which is kept up to
+	// date with the notes.
+	_, err := RebuildIndex()
+	assert.Nil(t, err)
+	ioutil.WriteFile(path.Join(dir, "new.md"), []byte("Kafka again\n"), 0644)
+	files, err := Find("kafka -tag:work")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{path.Join(dir, "home.md"), path.Join(dir, "new.md")}, files)
+}
+
+func TestQuerySyntaxError(t *testing.T) {
+	for query, pos := range map[string]int{
+		``:                    0,
+		`tag:work AND (kafka`: 19,
+		`kafka)`:              5,
+		`kafka OR`:            8,
+		`AND kafka`:           0,
+		`title:"retro`:        6,
+		`modified:>someday`:   10,
+		`size:big`:            5,
+		`tga:work`:            0,
+		`tag:`:                4,
+	} {
+		_, err := parseQuery(query, time.Now())
+		syntaxErr, ok := err.(*QuerySyntaxError)
+		if assert.True(t, ok, query) {
+			assert.Equal(t, pos, syntaxErr.Pos, query)
+		}
+	}
+	_, err := parseQuery("a AND (b", time.Now())
+	assert.Equal(t, "Expected ')' at position 9:\na AND (b\n        ^", err.Error())
+}
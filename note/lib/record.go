@@ -0,0 +1,70 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// NoteRecord describes a note for machine-readable output. Name is the
+// path relative to the notebook root. Tags and Title come from the front
+// matter and are left empty for encrypted notes.
+type NoteRecord struct {
+	Path     string      `json:"path"`
+	Name     string      `json:"name"`
+	Notebook string      `json:"notebook,omitempty"`
+	Size     int64       `json:"size"`
+	Mtime    time.Time   `json:"mtime"`
+	Atime    time.Time   `json:"atime"`
+	Tags     []string    `json:"tags"`
+	Title    string      `json:"title"`
+	Matches  []MatchLine `json:"matches,omitempty"`
+}
+
+// MatchLine is a line of a note matching a search, or a context line
+// around one.
+type MatchLine struct {
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Text    string `json:"text"`
+	Context bool   `json:"context,omitempty"`
+}
+
+// NewNoteRecord returns the record of a note.
+func NewNoteRecord(file string) (*NoteRecord, error) {
+	root, err := rootOf(file)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := os.Stat(file)
+	if err != nil {
+		return nil, err
+	}
+	rel, err := filepath.Rel(root, file)
+	if err != nil {
+		return nil, err
+	}
+	record := &NoteRecord{
+		Path:  file,
+		Name:  filepath.ToSlash(rel),
+		Size:  fi.Size(),
+		Mtime: fi.ModTime(),
+		// Read before the front matter, which updates it.
+		Atime: time.Unix(Atime(file), 0),
+		Tags:  []string{},
+	}
+	if !IsEncrypted(file) {
+		if meta, err := ParseMeta(file); err == nil {
+			record.Title = meta.Title
+			if meta.Tags != nil {
+				record.Tags = meta.Tags
+			}
+		}
+	}
+	return record, nil
+}
+
+// AddMatch adds a line returned by Grep to the record.
+func (r *NoteRecord) AddMatch(match Match) {
+	r.Matches = append(r.Matches, MatchLine{match.Line, match.Column, match.Text, match.Context})
+}
@@ -0,0 +1,35 @@
+package lib
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewNoteRecord(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "note")
+	defer os.RemoveAll(dir)
+	SetConfig(&Config{NotesDir: dir})
+	defer SetConfig(nil)
+	os.Mkdir(path.Join(dir, "work"), 0755)
+	file := path.Join(dir, "work", "plan.md")
+	ioutil.WriteFile(file, []byte("---\ntitle: Plan\ntags: [a, b]\n---\nfoo\n"), 0644)
+
+	record, err := NewNoteRecord(file)
+	assert.Nil(t, err)
+	assert.Equal(t, file, record.Path)
+	assert.Equal(t, "work/plan.md", record.Name)
+	assert.Equal(t, int64(37), record.Size)
+	assert.Equal(t, "Plan", record.Title)
+	assert.Equal(t, []string{"a", "b"}, record.Tags)
+	assert.Nil(t, record.Matches)
+
+	record.AddMatch(Match{File: file, Line: 5, Column: 1, Text: "foo"})
+	assert.Equal(t, []MatchLine{{5, 1, "foo", false}}, record.Matches)
+
+	_, err = NewNoteRecord(path.Join(dir, "missing.md"))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,405 @@
+package lib
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"io/fs"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp/syntax"
+	"strconv"
+	"strings"
+)
+
+// maxNoteSize bounds the body of a write to the API.
+const maxNoteSize = 8 << 20
+
+// ServerOptions configure the handler returned by NewServer. With a Token,
+// API requests must send it as "Authorization: Bearer <token>". Without
+// one, only requests addressed to a loopback name on the port of Addr, the
+// address listened on, are answered, so web pages can't reach the notes
+// through DNS rebinding.
+type ServerOptions struct {
+	Addr  string
+	Token string
+}
+
+// NoteContent is a note as returned by the API: its record, its text and
+// the text rendered as HTML.
+type NoteContent struct {
+	*NoteRecord
+	Content string `json:"content"`
+	HTML    string `json:"html"`
+	ETag    string `json:"etag"`
+}
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+type server struct {
+	addr  string
+	token string
+}
+
+// NewServer returns the handler of the web UI and of the REST API over the
+// selected notebook. Notes are addressed by their name relative to the
+// notebook root:
+//
+//	GET    /api/notes?q=name&tag=tag  list notes, like note ls
+//	GET    /api/search?q=pattern      search notes, like note grep
+//	GET    /api/notes/<name>          read a note
+//	PUT    /api/notes/<name>          create or replace a note
+//	DELETE /api/notes/<name>          move a note to the trash
+//
+// Replacing or deleting a note requires the ETag it was read with in an
+// If-Match header, so concurrent edits aren't lost. "If-None-Match: *" only
+// creates a note that doesn't exist yet.
+func NewServer(opts ServerOptions) http.Handler {
+	s := &server{opts.Addr, opts.Token}
+	web, _ := fs.Sub(siteFiles, "site/web")
+	mux := http.NewServeMux()
+	mux.Handle("/api/notes", s.auth(http.HandlerFunc(s.handleList)))
+	mux.Handle("/api/notes/", s.auth(http.HandlerFunc(s.handleNote)))
+	mux.Handle("/api/search", s.auth(http.HandlerFunc(s.handleSearch)))
+	mux.Handle("/", http.FileServer(http.FS(web)))
+	return s.checkHost(mux)
+}
+
+func (s *server) checkHost(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.token == "" && !s.loopbackHost(r.Host) {
+			writeError(w, http.StatusForbidden, "Without a token, requests must be addressed to a loopback address.")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// loopbackHost reports whether host, the Host header of a request, names a
+// loopback address on the port the server listens on.
+func (s *server) loopbackHost(host string) bool {
+	name, port, err := net.SplitHostPort(host)
+	if err != nil {
+		name, port = host, ""
+	}
+	if _, addrPort, err := net.SplitHostPort(s.addr); err == nil && port != addrPort {
+		return false
+	}
+	if name == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(name)
+	return ip != nil && ip.IsLoopback()
+}
+
+func (s *server) auth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.token != "" {
+			token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				writeError(w, http.StatusUnauthorized, "Missing or wrong token.")
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Print(err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, apiError{msg})
+}
+
+// errorStatus returns the status code an error of the lib functions is
+// answered with.
+func errorStatus(err error) int {
+	switch err.(type) {
+	case NoFilesError:
+		return http.StatusNotFound
+	case InvalidNameError, *QuerySyntaxError, *syntax.Error:
+		return http.StatusBadRequest
+	}
+	if os.IsNotExist(err) {
+		return http.StatusNotFound
+	}
+	if _, ok := err.(*os.PathError); ok {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func etag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+func (s *server) handleList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed.")
+		return
+	}
+	query := r.URL.Query()
+	files, err := ListWith(query.Get("q"), Filter{Tags: query["tag"]})
+	if err != nil {
+		writeError(w, errorStatus(err), err.Error())
+		return
+	}
+	records := []*NoteRecord{}
+	for _, file := range files {
+		if record, err := NewNoteRecord(file); err == nil {
+			records = append(records, record)
+		}
+	}
+	writeJSON(w, http.StatusOK, records)
+}
+
+func (s *server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed.")
+		return
+	}
+	query := r.URL.Query()
+	if query.Get("q") == "" {
+		writeError(w, http.StatusBadRequest, "No pattern given.")
+		return
+	}
+	opts := GrepOptions{
+		Regexp:     query.Get("regexp") == "true",
+		IgnoreCase: query.Get("ignore_case") == "true",
+		MaxCount:   1000,
+	}
+	if max, err := strconv.Atoi(query.Get("max")); err == nil && max > 0 && max < opts.MaxCount {
+		opts.MaxCount = max
+	}
+	records := []*NoteRecord{}
+	var record *NoteRecord
+	err := GrepContext(r.Context(), query.Get("q"), opts, func(match Match) error {
+		if record == nil || record.Path != match.File {
+			var err error
+			if record, err = NewNoteRecord(match.File); err != nil {
+				return err
+			}
+			records = append(records, record)
+		}
+		record.AddMatch(match)
+		return nil
+	})
+	if err != nil {
+		writeError(w, errorStatus(err), err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, records)
+}
+
+func (s *server) handleNote(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/api/notes/")
+	root, err := GetConfig().Root()
+	if err != nil {
+		writeError(w, errorStatus(err), err.Error())
+		return
+	}
+	file, err := notePath(root, name)
+	if err != nil {
+		writeError(w, errorStatus(err), err.Error())
+		return
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		s.readNote(w, file)
+	case http.MethodPut:
+		s.writeNote(w, r, root, file)
+	case http.MethodDelete:
+		s.deleteNote(w, r, root, file)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed.")
+	}
+}
+
+// currentETag returns the ETag of file as it is on disk, and an empty one
+// when it doesn't exist.
+func currentETag(file string) (string, error) {
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return etag(data), nil
+}
+
+// checkPreconditions compares the If-Match and If-None-Match headers of r
+// to the current ETag of a note, and answers the request when they fail.
+func checkPreconditions(w http.ResponseWriter, r *http.Request, current string) bool {
+	ifMatch := r.Header.Get("If-Match")
+	ifNoneMatch := r.Header.Get("If-None-Match")
+	switch {
+	case ifNoneMatch == "*" && current != "":
+		writeError(w, http.StatusPreconditionFailed, "The note already exists.")
+	case ifMatch != "" && ifMatch != current && !(ifMatch == "*" && current != ""):
+		writeError(w, http.StatusPreconditionFailed, "The note was changed since it was read.")
+	case ifMatch == "" && ifNoneMatch == "" && current != "":
+		writeError(w, http.StatusPreconditionRequired, "Changing a note requires the If-Match header.")
+	default:
+		return true
+	}
+	return false
+}
+
+func (s *server) readNote(w http.ResponseWriter, file string) {
+	fi, err := os.Stat(file)
+	if err == nil && fi.IsDir() {
+		err = NoFilesError(true)
+	}
+	if err != nil {
+		writeError(w, errorStatus(err), err.Error())
+		return
+	}
+	raw, err := ioutil.ReadFile(file)
+	if err != nil {
+		writeError(w, errorStatus(err), err.Error())
+		return
+	}
+	var k *keys
+	if IsEncrypted(file) {
+		if k, err = loadKeys(); err != nil {
+			writeError(w, errorStatus(err), err.Error())
+			return
+		}
+	}
+	data, err := readNote(file, k)
+	if err != nil {
+		writeError(w, errorStatus(err), err.Error())
+		return
+	}
+	record, err := NewNoteRecord(file)
+	if err != nil {
+		writeError(w, errorStatus(err), err.Error())
+		return
+	}
+	_, body := SplitFrontMatter(data)
+	var html bytes.Buffer
+	if err := siteMarkdown.Convert(wikiToMarkdown(body), &html); err != nil {
+		log.Print(err)
+	}
+	tag := etag(raw)
+	w.Header().Set("ETag", tag)
+	writeJSON(w, http.StatusOK, &NoteContent{record, string(data), html.String(), tag})
+}
+
+func (s *server) writeNote(w http.ResponseWriter, r *http.Request, root, file string) {
+	data, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxNoteSize))
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, err.Error())
+		return
+	}
+	unlock, err := lockNotebook(root)
+	if err != nil {
+		writeError(w, errorStatus(err), err.Error())
+		return
+	}
+	defer unlock()
+	current, err := currentETag(file)
+	if err != nil {
+		writeError(w, errorStatus(err), err.Error())
+		return
+	}
+	if !checkPreconditions(w, r, current) {
+		return
+	}
+	var k *keys
+	if IsEncrypted(file) {
+		if k, err = loadKeys(); err != nil {
+			writeError(w, errorStatus(err), err.Error())
+			return
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		writeError(w, errorStatus(err), err.Error())
+		return
+	}
+	if err := writeNote(file, data, k); err != nil {
+		writeError(w, errorStatus(err), err.Error())
+		return
+	}
+	if GetConfig().AutoCommit {
+		format := "Edit %s"
+		if current == "" {
+			format = "Create %s"
+		}
+		if err := commitNote(file, format); err != nil {
+			log.Print(err)
+		}
+	}
+	tag, err := currentETag(file)
+	if err != nil {
+		writeError(w, errorStatus(err), err.Error())
+		return
+	}
+	record, err := NewNoteRecord(file)
+	if err != nil {
+		writeError(w, errorStatus(err), err.Error())
+		return
+	}
+	status := http.StatusOK
+	if current == "" {
+		status = http.StatusCreated
+	}
+	w.Header().Set("ETag", tag)
+	writeJSON(w, status, record)
+}
+
+func (s *server) deleteNote(w http.ResponseWriter, r *http.Request, root, file string) {
+	entry := s.trashNote(w, r, root, file)
+	if entry == nil {
+		return
+	}
+	removed(root, file, entry)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// trashNote moves a note to the trash when the preconditions of r hold,
+// with the notebook locked. It answers the request and returns nil when
+// they don't.
+func (s *server) trashNote(w http.ResponseWriter, r *http.Request, root, file string) *TrashEntry {
+	unlock, err := lockNotebook(root)
+	if err != nil {
+		writeError(w, errorStatus(err), err.Error())
+		return nil
+	}
+	defer unlock()
+	current, err := currentETag(file)
+	if err != nil {
+		writeError(w, errorStatus(err), err.Error())
+		return nil
+	}
+	if current == "" {
+		writeError(w, http.StatusNotFound, NoFilesError(true).Error())
+		return nil
+	}
+	if !checkPreconditions(w, r, current) {
+		return nil
+	}
+	entry, err := trashFile(root, file)
+	if err != nil {
+		writeError(w, errorStatus(err), err.Error())
+		return nil
+	}
+	return entry
+}
@@ -0,0 +1,128 @@
+package lib
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func request(t *testing.T, handler http.Handler, method, url, body string, headers map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, url, strings.NewReader(body))
+	r.Host = "127.0.0.1:8080"
+	for name, value := range headers {
+		if name == "Host" {
+			r.Host = value
+		}
+		r.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestServer(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "note")
+	defer os.RemoveAll(dir)
+	SetConfig(&Config{NotesDir: dir, Sort: SortName})
+	defer SetConfig(nil)
+	ioutil.WriteFile(path.Join(dir, "plan.md"), []byte("---\ntags: [work]\n---\n# Plan\nship it\n"), 0644)
+	handler := NewServer(ServerOptions{Addr: "127.0.0.1:8080"})
+
+	// List and search.
+	w := request(t, handler, "GET", "/api/notes?tag=work", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var records []NoteRecord
+	json.Unmarshal(w.Body.Bytes(), &records)
+	assert.Equal(t, 1, len(records))
+	assert.Equal(t, "plan.md", records[0].Name)
+	w = request(t, handler, "GET", "/api/search?q=ship", "", nil)
+	records = nil
+	json.Unmarshal(w.Body.Bytes(), &records)
+	assert.Equal(t, []MatchLine{{5, 1, "ship it", false}}, records[0].Matches)
+	w = request(t, handler, "GET", "/api/search?q=(&regexp=true", "", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Without a token, only requests to the loopback address listened on
+	// are answered, which DNS rebinding can't fake.
+	for host, code := range map[string]int{
+		"localhost:8080":    http.StatusOK,
+		"[::1]:8080":        http.StatusOK,
+		"127.0.0.1:9999":    http.StatusForbidden,
+		"evil.example:8080": http.StatusForbidden,
+		"evil.example":      http.StatusForbidden,
+		"192.168.1.10:8080": http.StatusForbidden,
+	} {
+		w = request(t, handler, "GET", "/api/notes", "", map[string]string{"Host": host})
+		assert.Equal(t, code, w.Code, host)
+	}
+
+	// Read.
+	w = request(t, handler, "GET", "/api/notes/plan.md", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var note NoteContent
+	json.Unmarshal(w.Body.Bytes(), &note)
+	assert.Equal(t, "plan.md", note.Name)
+	assert.Contains(t, note.Content, "ship it")
+	assert.Contains(t, note.HTML, "<h1 id=\"plan\">Plan</h1>")
+	assert.Equal(t, w.Header().Get("ETag"), note.ETag)
+	assert.Equal(t, http.StatusNotFound, request(t, handler, "GET", "/api/notes/missing.md", "", nil).Code)
+	assert.Equal(t, http.StatusBadRequest, request(t, handler, "GET", "/api/notes/.git/config", "", nil).Code)
+
+	// Writes need the current ETag.
+	w = request(t, handler, "PUT", "/api/notes/plan.md", "new", nil)
+	assert.Equal(t, http.StatusPreconditionRequired, w.Code)
+	w = request(t, handler, "PUT", "/api/notes/plan.md", "new", map[string]string{"If-Match": `"stale"`})
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	w = request(t, handler, "PUT", "/api/notes/plan.md", "new", map[string]string{"If-Match": note.ETag})
+	assert.Equal(t, http.StatusOK, w.Code)
+	data, _ := ioutil.ReadFile(path.Join(dir, "plan.md"))
+	assert.Equal(t, "new", string(data))
+	newETag := w.Header().Get("ETag")
+	assert.NotEqual(t, note.ETag, newETag)
+	w = request(t, handler, "PUT", "/api/notes/plan.md", "newer", map[string]string{"If-Match": note.ETag})
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	// Create, only when missing with If-None-Match.
+	w = request(t, handler, "PUT", "/api/notes/work/todo.md", "todo", map[string]string{"If-None-Match": "*"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	w = request(t, handler, "PUT", "/api/notes/work/todo.md", "todo", map[string]string{"If-None-Match": "*"})
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	// Delete moves the note to the trash.
+	w = request(t, handler, "DELETE", "/api/notes/plan.md", "", map[string]string{"If-Match": note.ETag})
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	w = request(t, handler, "DELETE", "/api/notes/plan.md", "", map[string]string{"If-Match": newETag})
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	_, err := os.Stat(path.Join(dir, "plan.md"))
+	assert.True(t, os.IsNotExist(err))
+	trash, _ := ListTrash()
+	assert.Equal(t, 1, len(trash))
+
+	// The web UI is served too.
+	w = request(t, handler, "GET", "/", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "app.js")
+}
+
+func TestServerToken(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "note")
+	defer os.RemoveAll(dir)
+	SetConfig(&Config{NotesDir: dir})
+	defer SetConfig(nil)
+	handler := NewServer(ServerOptions{Token: "secret"})
+
+	assert.Equal(t, http.StatusUnauthorized, request(t, handler, "GET", "/api/notes", "", nil).Code)
+	assert.Equal(t, http.StatusUnauthorized, request(t, handler, "GET", "/api/notes", "", map[string]string{"Authorization": "Bearer wrong"}).Code)
+	assert.Equal(t, http.StatusOK, request(t, handler, "GET", "/api/notes", "", map[string]string{"Authorization": "Bearer secret"}).Code)
+	// With a token, any host name works.
+	assert.Equal(t, http.StatusOK, request(t, handler, "GET", "/api/notes", "", map[string]string{"Authorization": "Bearer secret", "Host": "notes.example"}).Code)
+	// The UI itself holds no notes and loads without the token.
+	assert.Equal(t, http.StatusOK, request(t, handler, "GET", "/app.js", "", nil).Code)
+}
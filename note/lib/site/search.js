@@ -0,0 +1,50 @@
+// Searches the notes of the site with the index in _search.js, listing the
+// notes containing every word typed, the ones with the words in their
+// title first.
+(function () {
+  var input = document.getElementById("search");
+  var results = document.getElementById("results");
+  var root = document.body.getAttribute("data-root");
+  if (!input || typeof searchIndex === "undefined") {
+    return;
+  }
+  var entries = searchIndex.map(function (entry) {
+    return {
+      entry: entry,
+      title: entry.title.toLowerCase(),
+      text: (entry.title + " " + entry.tags.join(" ") + " " + entry.text).toLowerCase()
+    };
+  });
+  input.addEventListener("input", function () {
+    var words = input.value.toLowerCase().split(/\s+/).filter(Boolean);
+    results.innerHTML = "";
+    results.hidden = words.length === 0;
+    var hits = [];
+    entries.forEach(function (e) {
+      var score = 0;
+      for (var i = 0; i < words.length; i++) {
+        if (e.text.indexOf(words[i]) < 0) {
+          return;
+        }
+        score += e.title.indexOf(words[i]) >= 0 ? 10 : 1;
+      }
+      hits.push({ entry: e.entry, score: score });
+    });
+    hits.sort(function (a, b) {
+      return b.score - a.score;
+    });
+    hits.slice(0, 20).forEach(function (hit) {
+      var item = document.createElement("li");
+      var link = document.createElement("a");
+      link.href = root + hit.entry.url;
+      link.textContent = hit.entry.title;
+      item.appendChild(link);
+      results.appendChild(item);
+    });
+    if (words.length > 0 && hits.length === 0) {
+      var item = document.createElement("li");
+      item.textContent = "No notes found.";
+      results.appendChild(item);
+    }
+  });
+})();
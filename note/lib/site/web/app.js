@@ -0,0 +1,210 @@
+// The web UI of note serve: lists and searches notes, shows them and edits
+// them through the REST API. Saves send the ETag the note was read with,
+// so a note changed meanwhile isn't overwritten.
+(function () {
+  var $ = function (id) {
+    return document.getElementById(id);
+  };
+  var current = null;
+
+  function token() {
+    return localStorage.getItem("note-token") || "";
+  }
+
+  // api calls the REST API, asking for the token when the server wants one.
+  function api(method, path, body, headers) {
+    var init = { method: method, headers: headers || {} };
+    if (token()) {
+      init.headers.Authorization = "Bearer " + token();
+    }
+    if (body !== undefined) {
+      init.body = body;
+    }
+    return fetch(path, init).then(function (resp) {
+      if (resp.status === 401) {
+        var t = prompt("Token");
+        if (t === null) {
+          throw new Error("Missing or wrong token.");
+        }
+        localStorage.setItem("note-token", t);
+        return api(method, path, body, headers);
+      }
+      if (resp.status === 204) {
+        return { resp: resp, data: null };
+      }
+      return resp.json().then(function (data) {
+        if (!resp.ok) {
+          var err = new Error(data.error || resp.statusText);
+          err.status = resp.status;
+          throw err;
+        }
+        return { resp: resp, data: data };
+      });
+    });
+  }
+
+  function notePath(name) {
+    return "/api/notes/" + name.split("/").map(encodeURIComponent).join("/");
+  }
+
+  function message(text) {
+    $("message").textContent = text || "";
+    $("message").hidden = !text;
+  }
+
+  function listNotes() {
+    var q = $("query").value;
+    var path = $("grep").checked && q ? "/api/search?ignore_case=true&q=" : "/api/notes?q=";
+    api("GET", path + encodeURIComponent(q))
+      .then(function (result) {
+        var list = $("notes");
+        list.innerHTML = "";
+        result.data.slice().reverse().forEach(function (note) {
+          var item = document.createElement("li");
+          item.textContent = note.name;
+          item.className = current && current.name === note.name ? "current" : "";
+          (note.matches || []).slice(0, 3).forEach(function (match) {
+            var line = document.createElement("span");
+            line.className = "match";
+            line.textContent = match.line + ": " + match.text;
+            item.appendChild(line);
+          });
+          item.onclick = function () {
+            openNote(note.name);
+          };
+          list.appendChild(item);
+        });
+      })
+      .catch(function (err) {
+        message(err.message);
+      });
+  }
+
+  function showNote(note) {
+    current = note;
+    $("toolbar").hidden = false;
+    $("name").textContent = note.name;
+    $("view").innerHTML = note.html;
+    setEditing(false);
+    location.hash = note.name;
+  }
+
+  function openNote(name) {
+    message("");
+    return api("GET", notePath(name))
+      .then(function (result) {
+        showNote(result.data);
+        listNotes();
+      })
+      .catch(function (err) {
+        message(err.message);
+      });
+  }
+
+  function setEditing(editing) {
+    $("view").hidden = editing;
+    $("editor").hidden = !editing;
+    $("edit").hidden = editing;
+    $("delete").hidden = editing;
+    $("save").hidden = !editing;
+    $("cancel").hidden = !editing;
+    if (editing) {
+      $("editor").value = current.content;
+      $("editor").focus();
+    }
+  }
+
+  // Links between notes open the linked note in the UI.
+  $("view").addEventListener("click", function (event) {
+    var link = event.target.closest("a");
+    if (!link) {
+      return;
+    }
+    var href = link.getAttribute("href") || "";
+    if (/^[a-z][a-z0-9+.-]*:/i.test(href) && href.indexOf("wiki:") !== 0) {
+      return;
+    }
+    event.preventDefault();
+    var target = decodeURIComponent(href.replace(/^wiki:/, "").split("#")[0]);
+    if (!target) {
+      return;
+    }
+    api("GET", "/api/notes?q=" + encodeURIComponent(target))
+      .then(function (result) {
+        if (result.data.length === 0) {
+          message("No note matches " + target + ".");
+          return;
+        }
+        openNote(result.data[result.data.length - 1].name);
+      })
+      .catch(function (err) {
+        message(err.message);
+      });
+  });
+
+  $("find").addEventListener("submit", function (event) {
+    event.preventDefault();
+    listNotes();
+  });
+  $("query").addEventListener("input", function () {
+    if (!$("grep").checked) {
+      listNotes();
+    }
+  });
+  $("grep").addEventListener("change", listNotes);
+  $("edit").onclick = function () {
+    setEditing(true);
+  };
+  $("cancel").onclick = function () {
+    setEditing(false);
+  };
+  $("save").onclick = function () {
+    api("PUT", notePath(current.name), $("editor").value, { "If-Match": current.etag })
+      .then(function () {
+        return openNote(current.name);
+      })
+      .catch(function (err) {
+        if (err.status === 412) {
+          message("The note was changed since you opened it. Copy your changes, then reload it.");
+        } else {
+          message(err.message);
+        }
+      });
+  };
+  $("delete").onclick = function () {
+    if (!confirm("Move " + current.name + " to the trash?")) {
+      return;
+    }
+    api("DELETE", notePath(current.name), undefined, { "If-Match": current.etag })
+      .then(function () {
+        current = null;
+        $("toolbar").hidden = true;
+        $("view").innerHTML = "";
+        location.hash = "";
+        listNotes();
+      })
+      .catch(function (err) {
+        message(err.message);
+      });
+  };
+  $("new").onclick = function () {
+    var name = prompt("Name of the new note, like folder/name.md");
+    if (!name) {
+      return;
+    }
+    api("PUT", notePath(name), "", { "If-None-Match": "*" })
+      .then(function () {
+        return openNote(name).then(function () {
+          setEditing(true);
+        });
+      })
+      .catch(function (err) {
+        message(err.message);
+      });
+  };
+
+  listNotes();
+  if (location.hash.length > 1) {
+    openNote(decodeURIComponent(location.hash.slice(1)));
+  }
+})();
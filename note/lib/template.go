@@ -0,0 +1,192 @@
+package lib
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+type TemplateNotFoundError string
+type MissingVarError string
+
+func (e TemplateNotFoundError) Error() string {
+	return "No template named '" + string(e) + "'."
+}
+
+func (e MissingVarError) Error() string {
+	return "No value given for template variable '" + string(e) + "'."
+}
+
+// TemplateData is what templates are executed with. Besides its fields,
+// templates can ask for custom values with {{var "name"}}.
+type TemplateData struct {
+	Date     string
+	Time     string
+	Title    string
+	User     string
+	Notebook string
+}
+
+// TemplatesDir returns the directory holding the templates of the selected
+// notebook.
+func TemplatesDir() (string, error) {
+	conf := GetConfig()
+	if conf.TemplatesDir != "" {
+		return conf.TemplatesDir, nil
+	}
+	root, err := conf.Root()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, stateDir, "templates"), nil
+}
+
+func templateName(file string) string {
+	base := filepath.Base(file)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// ListTemplates returns the names of the available templates.
+func ListTemplates() ([]string, error) {
+	dir, err := TemplatesDir()
+	if err != nil {
+		return nil, err
+	}
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	var names []string
+	for _, info := range infos {
+		if !info.IsDir() {
+			names = append(names, templateName(info.Name()))
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// TemplateFile returns the file of the named template, which may be given
+// with or without its extension. Names must stay inside the templates
+// directory.
+func TemplateFile(name string) (string, error) {
+	dir, err := TemplatesDir()
+	if err != nil {
+		return "", err
+	}
+	file := filepath.Join(dir, name)
+	if rel, ok := relPath(dir, file); !ok || rel == "." {
+		return "", InvalidNameError(name)
+	}
+	if fi, err := os.Stat(file); err == nil && !fi.IsDir() {
+		return file, nil
+	}
+	matches, _ := filepath.Glob(filepath.Join(dir, name+".*"))
+	if len(matches) == 0 {
+		return "", TemplateNotFoundError(name)
+	}
+	return matches[0], nil
+}
+
+// EditTemplate opens the named template in the editor, creating it as a
+// markdown file when it doesn't exist.
+func EditTemplate(name string) error {
+	editor := strings.Fields(GetConfig().Editor)
+	if len(editor) == 0 {
+		return EditorNotSetError(true)
+	}
+	file, err := TemplateFile(name)
+	if _, ok := err.(TemplateNotFoundError); ok {
+		dir, err := TemplatesDir()
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		file = filepath.Join(dir, name)
+		if filepath.Ext(name) == "" {
+			file += ".md"
+		}
+	} else if err != nil {
+		return err
+	}
+	return runEditor(editor, file)
+}
+
+// defaultTemplate returns the template configured for the directory of the
+// note rel, looking at the closest parent directory first. The root of the
+// notebook is ".".
+func defaultTemplate(rel string) string {
+	defaults := GetConfig().DefaultTemplates
+	for dir := filepath.Dir(rel); ; dir = filepath.Dir(dir) {
+		// Directories are compared ignoring case as viper lower-cases
+		// the keys of the config file.
+		for defaultDir, name := range defaults {
+			if strings.EqualFold(filepath.Clean(defaultDir), dir) {
+				return name
+			}
+		}
+		if dir == "." || dir == "/" {
+			return ""
+		}
+	}
+}
+
+// RenderTemplate executes the named template for the note at rel. Custom
+// variables are taken from vars, and prompt is asked for the missing ones.
+func RenderTemplate(name, rel string, vars map[string]string, prompt func(string) (string, error)) ([]byte, error) {
+	file, err := TemplateFile(name)
+	if err != nil {
+		return nil, err
+	}
+	text, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string)
+	for k, v := range vars {
+		values[k] = v
+	}
+	funcs := template.FuncMap{
+		"var": func(key string) (string, error) {
+			if value, ok := values[key]; ok {
+				return value, nil
+			}
+			if prompt == nil {
+				return "", MissingVarError(key)
+			}
+			value, err := prompt(key)
+			if err != nil {
+				return "", err
+			}
+			values[key] = value
+			return value, nil
+		},
+	}
+	tmpl, err := template.New(name).Funcs(funcs).Parse(string(text))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	data := TemplateData{
+		Date:     now.Format("2006-01-02"),
+		Time:     now.Format("15:04"),
+		Title:    templateName(rel),
+		Notebook: GetConfig().Notebook,
+	}
+	if u, err := user.Current(); err == nil {
+		data.User = u.Username
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
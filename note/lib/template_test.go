@@ -0,0 +1,66 @@
+package lib
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplates(t *testing.T) {
+	dir, _ := createTestFiles()
+	defer os.RemoveAll(dir)
+	SetConfig(&Config{
+		NotesDir:         dir,
+		Editor:           "true",
+		DefaultTemplates: map[string]string{"Meetings": "meeting"},
+	})
+	defer SetConfig(nil)
+
+	names, err := ListTemplates()
+	assert.Nil(t, err)
+	assert.Nil(t, names)
+	err = EditWith("foo", EditOptions{Create: true, Template: "meeting"})
+	assert.Equal(t, TemplateNotFoundError("meeting"), err)
+
+	templatesDir, _ := TemplatesDir()
+	os.MkdirAll(templatesDir, 0755)
+	ioutil.WriteFile(path.Join(templatesDir, "meeting.md"),
+		[]byte("# {{.Title}} {{.Date}}\nclient: {{var \"client\"}} {{var \"client\"}}\n"), 0644)
+	names, _ = ListTemplates()
+	assert.Equal(t, []string{"meeting"}, names)
+
+	// Templates can't be read or written outside the templates directory.
+	ioutil.WriteFile(path.Join(dir, ".note", "secret.md"), []byte("secret\n"), 0644)
+	_, err = TemplateFile("../secret")
+	assert.Equal(t, InvalidNameError("../secret"), err)
+	assert.Equal(t, InvalidNameError("../secret.md"), EditTemplate("../secret.md"))
+
+	// Custom variables are prompted for once.
+	prompts := 0
+	prompt := func(name string) (string, error) {
+		prompts++
+		return "acme", nil
+	}
+	err = EditWith("standup", EditOptions{Create: true, Template: "meeting", Prompt: prompt})
+	assert.Nil(t, err)
+	data, _ := ioutil.ReadFile(path.Join(dir, "standup"))
+	assert.Equal(t, "# standup "+time.Now().Format("2006-01-02")+"\nclient: acme acme\n", string(data))
+	assert.Equal(t, 1, prompts)
+
+	// Missing variables are reported without a prompt.
+	err = EditWith("retro", EditOptions{Create: true, Template: "meeting"})
+	assert.NotNil(t, err)
+	assert.True(t, strings.Contains(err.Error(), MissingVarError("client").Error()))
+
+	// Directories can have a default template.
+	os.Mkdir(path.Join(dir, "meetings"), 0755)
+	err = EditWith("meetings/sync", EditOptions{Create: true, Vars: map[string]string{"client": "foo"}})
+	assert.Nil(t, err)
+	data, _ = ioutil.ReadFile(path.Join(dir, "meetings", "sync"))
+	assert.True(t, strings.HasPrefix(string(data), "# sync "))
+}
@@ -0,0 +1,246 @@
+package lib
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Deleted notes are moved to the trash directory of their notebook. The
+// note itself goes to files/<id> and its original path and deletion time
+// to info/<id>.json.
+const trashDir = ".trash"
+
+type InvalidAgeError string
+
+func (e InvalidAgeError) Error() string {
+	return "Can't understand the age '" + string(e) + "'."
+}
+
+type TrashEntry struct {
+	ID      string    `json:"-"`
+	Path    string    `json:"path"`
+	Deleted time.Time `json:"deleted"`
+	File    string    `json:"-"`
+}
+
+var ageRegex = regexp.MustCompile(`^(\d+)([dw])$`)
+
+// ParseAge parses durations like "30d" and "2w" besides the ones understood
+// by time.ParseDuration.
+func ParseAge(s string) (time.Duration, error) {
+	if m := ageRegex.FindStringSubmatch(s); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		days := time.Duration(n) * 24 * time.Hour
+		if m[2] == "w" {
+			days *= 7
+		}
+		return days, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, InvalidAgeError(s)
+	}
+	return d, nil
+}
+
+func trashPaths(root, id string) (string, string) {
+	return filepath.Join(root, trashDir, "files", id), filepath.Join(root, trashDir, "info", id+".json")
+}
+
+// trashFile moves a file of the notebook rooted at root to its trash.
+func trashFile(root, file string) (*TrashEntry, error) {
+	rel, err := filepath.Rel(root, file)
+	if err != nil {
+		return nil, err
+	}
+	for _, dir := range []string{"files", "info"} {
+		if err := os.MkdirAll(filepath.Join(root, trashDir, dir), 0755); err != nil {
+			return nil, err
+		}
+	}
+	entry := &TrashEntry{Path: rel, Deleted: time.Now()}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+	// Reserve a free id by creating its info file.
+	base := filepath.Base(file)
+	var info string
+	for i := 0; ; i++ {
+		entry.ID = base
+		if i > 0 {
+			entry.ID = base + "." + strconv.Itoa(i)
+		}
+		entry.File, info = trashPaths(root, entry.ID)
+		f, err := os.OpenFile(info, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if os.IsExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		_, err = f.Write(data)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(info)
+			return nil, err
+		}
+		break
+	}
+	if err := os.Rename(file, entry.File); err != nil {
+		os.Remove(info)
+		return nil, err
+	}
+	return entry, nil
+}
+
+// Remove moves the note matching name to the trash.
+func Remove(name string) (*TrashEntry, error) {
+	file, err := Resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	return removeFile(file)
+}
+
+// removeFile moves a note to the trash of its notebook.
+func removeFile(file string) (*TrashEntry, error) {
+	root, err := rootOf(file)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := trashFile(root, file)
+	if err != nil {
+		return nil, err
+	}
+	removed(root, file, entry)
+	return entry, nil
+}
+
+// removed forgets the access history of a note moved to the trash and
+// commits its removal. It takes the notebook lock, so callers holding it
+// call it once they released it.
+func removed(root, file string, entry *TrashEntry) {
+	if err := moveAccess(root, file, ""); err != nil {
+		log.Print(err)
+	}
+	if GetConfig().AutoCommit {
+		if err := commitFiles(root, []string{entry.Path}, "Remove "+entry.Path); err != nil {
+			log.Print(err)
+		}
+	}
+}
+
+// ListTrash returns the notes in the trash of the selected notebook, most
+// recently deleted first.
+func ListTrash() ([]TrashEntry, error) {
+	root, err := GetConfig().Root()
+	if err != nil {
+		return nil, err
+	}
+	infos, err := filepath.Glob(filepath.Join(root, trashDir, "info", "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var entries []TrashEntry
+	for _, info := range infos {
+		data, err := ioutil.ReadFile(info)
+		if err != nil {
+			log.Print(err)
+			continue
+		}
+		var entry TrashEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			log.Print(err)
+			continue
+		}
+		entry.ID = strings.TrimSuffix(filepath.Base(info), ".json")
+		entry.File, _ = trashPaths(root, entry.ID)
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Deleted.After(entries[j].Deleted)
+	})
+	return entries, nil
+}
+
+// RestoreTrash moves the note in the trash matching name, by its original
+// path or its id, back to where it was and returns its path.
+func RestoreTrash(name string) (string, error) {
+	entries, err := ListTrash()
+	if err != nil {
+		return "", err
+	}
+	var matching []TrashEntry
+	for _, entry := range entries {
+		if strings.Contains(entry.Path, name) || entry.ID == name {
+			matching = append(matching, entry)
+		}
+	}
+	if len(matching) == 0 {
+		return "", NoFilesError(true)
+	} else if len(matching) > 1 {
+		var files []string
+		for _, entry := range matching {
+			files = append(files, entry.ID)
+		}
+		return "", &MultipleFilesError{files}
+	}
+	entry := matching[0]
+	root, _ := GetConfig().Root()
+	file := filepath.Join(root, entry.Path)
+	if _, err := os.Lstat(file); err == nil {
+		return "", TargetExistsError(file)
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return "", err
+	}
+	if err := os.Rename(entry.File, file); err != nil {
+		return "", err
+	}
+	_, info := trashPaths(root, entry.ID)
+	if err := os.Remove(info); err != nil {
+		log.Print(err)
+	}
+	if GetConfig().AutoCommit {
+		if err := commitFiles(root, []string{entry.Path}, "Restore "+entry.Path+" from trash"); err != nil {
+			log.Print(err)
+		}
+	}
+	return file, nil
+}
+
+// EmptyTrash permanently deletes the notes that have been in the trash for
+// longer than olderThan and returns them.
+func EmptyTrash(olderThan time.Duration) ([]TrashEntry, error) {
+	entries, err := ListTrash()
+	if err != nil {
+		return nil, err
+	}
+	root, _ := GetConfig().Root()
+	cutoff := time.Now().Add(-olderThan)
+	var removed []TrashEntry
+	for _, entry := range entries {
+		if entry.Deleted.After(cutoff) {
+			continue
+		}
+		if err := os.RemoveAll(entry.File); err != nil {
+			return removed, err
+		}
+		_, info := trashPaths(root, entry.ID)
+		if err := os.Remove(info); err != nil {
+			return removed, err
+		}
+		removed = append(removed, entry)
+	}
+	return removed, nil
+}
@@ -0,0 +1,77 @@
+package lib
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrash(t *testing.T) {
+	dir, files := createTestFiles()
+	defer os.RemoveAll(dir)
+	os.Setenv("NOTES_DIR", dir)
+	os.Mkdir(path.Join(dir, "sub"), 0755)
+	for _, name := range []string{"foo", "sub/foo"} {
+		f, _ := os.Create(path.Join(dir, name))
+		f.Close()
+	}
+
+	// Ambiguous and missing names are reported like Edit does.
+	_, err := Remove("foo")
+	_, ok := err.(*MultipleFilesError)
+	assert.True(t, ok)
+	_, err = Remove("bar")
+	assert.Equal(t, NoFilesError(true), err)
+
+	entry, err := Remove("sub/foo")
+	assert.Nil(t, err)
+	assert.Equal(t, "sub/foo", entry.Path)
+	_, err = Remove(path.Base(files[0]))
+	assert.Nil(t, err)
+	_, err = Remove(path.Join(dir, "foo"))
+	assert.Nil(t, err)
+
+	// Trashed notes are not listed, and ids don't collide.
+	returnedFileNames, _ := List("")
+	assert.Equal(t, 9, len(returnedFileNames))
+	entries, err := ListTrash()
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(entries))
+	assert.Equal(t, "foo", entries[0].Path)
+	assert.Equal(t, "foo.1", entries[0].ID)
+
+	// Restore puts notes back where they were.
+	_, err = RestoreTrash("foo")
+	_, ok = err.(*MultipleFilesError)
+	assert.True(t, ok)
+	file, err := RestoreTrash("sub/foo")
+	assert.Nil(t, err)
+	assert.Equal(t, path.Join(dir, "sub/foo"), file)
+	_, err = os.Stat(file)
+	assert.Nil(t, err)
+
+	// Only old enough notes are deleted for good.
+	removed, err := EmptyTrash(time.Hour)
+	assert.Nil(t, err)
+	assert.Nil(t, removed)
+	removed, err = EmptyTrash(0)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(removed))
+	entries, _ = ListTrash()
+	assert.Nil(t, entries)
+}
+
+func TestParseAge(t *testing.T) {
+	age, err := ParseAge("30d")
+	assert.Nil(t, err)
+	assert.Equal(t, 30*24*time.Hour, age)
+	age, _ = ParseAge("2w")
+	assert.Equal(t, 14*24*time.Hour, age)
+	age, _ = ParseAge("90m")
+	assert.Equal(t, 90*time.Minute, age)
+	_, err = ParseAge("soon")
+	assert.Equal(t, InvalidAgeError("soon"), err)
+}
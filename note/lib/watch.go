@@ -0,0 +1,394 @@
+package lib
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// The watch daemon keeps what other commands would compute by reading every
+// note: the list of notes, their tags and links, and the search index. It
+// follows the notebook with inotify and answers queries on a Unix socket in
+// the state directory.
+const watchSocket = "watch.sock"
+
+// DefaultDebounce is how long the daemon waits for changes to settle before
+// updating, so the bursts of writes editors make when saving are handled
+// once.
+const DefaultDebounce = 200 * time.Millisecond
+
+// maxDebounces bounds, in debounce delays, how long a steady stream of
+// changes can postpone an update.
+const maxDebounces = 10
+
+// editorTempFiles are the swap, backup and probe files editors write next
+// to the notes they save. Changes to them never trigger an update.
+var editorTempFiles = []string{"*.sw[a-p]", "*.swx", "*~", "4913", ".#*", "#*#"}
+
+type DaemonRunningError string
+
+func (e DaemonRunningError) Error() string {
+	return "A watch daemon is already running for '" + string(e) + "'."
+}
+
+func socketPath(root string) string {
+	return filepath.Join(root, stateDir, watchSocket)
+}
+
+// WatchStatus describes a running watch daemon.
+type WatchStatus struct {
+	Pid     int       `json:"pid"`
+	Root    string    `json:"root"`
+	Notes   int       `json:"notes"`
+	Started time.Time `json:"started"`
+	Updated time.Time `json:"updated"`
+}
+
+// watchedNote is what the daemon knows of a note. Tags is nil when the
+// front matter couldn't be parsed, which leaves the note out of tag queries
+// like ListWith does.
+type watchedNote struct {
+	mtime int64
+	size  int64
+	tags  []string
+	links []Link
+}
+
+type watcher struct {
+	root     string
+	ignore   []string
+	debounce time.Duration
+	fsw      *fsnotify.Watcher
+	keys     *keys
+	started  time.Time
+
+	mu      sync.Mutex
+	pending bool
+	updated time.Time
+	notes   map[string]*watchedNote
+	files   []string
+	idx     *Index
+}
+
+// Watch runs the watch daemon of the selected notebook until ctx is done.
+// A debounce of 0 stands for DefaultDebounce.
+func Watch(ctx context.Context, debounce time.Duration) error {
+	conf := GetConfig()
+	root, err := conf.Root()
+	if err != nil {
+		return err
+	}
+	if debounce <= 0 {
+		debounce = DefaultDebounce
+	}
+	if err := os.MkdirAll(filepath.Join(root, stateDir), 0755); err != nil {
+		return err
+	}
+	// A socket nobody answers on is left over by a daemon that died.
+	if _, err := queryDaemon(root, daemonRequest{Op: "status"}); err == nil {
+		return DaemonRunningError(root)
+	}
+	os.Remove(socketPath(root))
+	ln, err := net.Listen("unix", socketPath(root))
+	if err != nil {
+		return err
+	}
+	defer ln.Close()
+	fsw, err := fsnotify.NewBufferedWatcher(1024)
+	if err != nil {
+		return err
+	}
+	defer fsw.Close()
+	k, err := loadKeys()
+	if err != nil {
+		return err
+	}
+	idx := loadIndex(root)
+	if idx == nil {
+		idx = newIndex(root)
+	}
+	w := &watcher{
+		root:     root,
+		ignore:   conf.Ignore,
+		debounce: debounce,
+		fsw:      fsw,
+		keys:     k,
+		started:  time.Now(),
+		notes:    make(map[string]*watchedNote),
+		idx:      idx,
+	}
+	w.mu.Lock()
+	w.update()
+	w.mu.Unlock()
+	go w.serve(ln)
+	return w.loop(ctx)
+}
+
+// relevant reports whether event may change what the daemon knows.
+func (w *watcher) relevant(event fsnotify.Event) bool {
+	if event.Op == fsnotify.Chmod {
+		return false
+	}
+	rel, ok := relPath(w.root, event.Name)
+	if !ok || rel == "." {
+		return true
+	}
+	if internalDirs[strings.Split(rel, string(filepath.Separator))[0]] {
+		return false
+	}
+	return !ignored(rel, w.ignore) && !ignored(filepath.Base(rel), editorTempFiles)
+}
+
+func (w *watcher) setPending() {
+	w.mu.Lock()
+	w.pending = true
+	w.mu.Unlock()
+}
+
+// loop waits for changes to settle and then updates, until ctx is done.
+func (w *watcher) loop(ctx context.Context) error {
+	timer := time.NewTimer(w.debounce)
+	timer.Stop()
+	var first time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-w.fsw.Events:
+			if !ok {
+				return nil
+			}
+			if !w.relevant(event) {
+				continue
+			}
+			w.setPending()
+			now := time.Now()
+			if first.IsZero() {
+				first = now
+			}
+			delay := w.debounce
+			if limit := first.Add(maxDebounces * w.debounce).Sub(now); limit < delay {
+				delay = limit
+			}
+			timer.Stop()
+			timer.Reset(delay)
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return nil
+			}
+			// Events were lost when the queue overflowed, which a
+			// rescan makes up for.
+			log.Print(err)
+			w.setPending()
+			timer.Stop()
+			timer.Reset(w.debounce)
+		case <-timer.C:
+			first = time.Time{}
+			w.mu.Lock()
+			if w.pending {
+				w.update()
+			}
+			w.mu.Unlock()
+		}
+	}
+}
+
+// catchUp takes in the events inotify already reported and applies them,
+// so a query sees the changes made before it without waiting for the
+// debounce delay.
+func (w *watcher) catchUp() {
+	for drained := false; !drained; {
+		select {
+		case event, ok := <-w.fsw.Events:
+			if !ok {
+				drained = true
+			} else if w.relevant(event) {
+				w.setPending()
+			}
+		default:
+			drained = true
+		}
+	}
+	w.mu.Lock()
+	if w.pending {
+		w.update()
+	}
+	w.mu.Unlock()
+}
+
+// update rescans the notebook, re-reading only the notes whose size or
+// mtime changed, and brings the watches, the caches, the search index and
+// the access log in line with it. Rescanning instead of replaying events
+// keeps it right after directory renames and lost events. w.mu must be
+// held.
+func (w *watcher) update() {
+	w.pending = false
+	dirs := map[string]bool{w.root: true}
+	var files []string
+	walkNotes(w.root, w.ignore, func(path string, info os.FileInfo) {
+		if info.IsDir() {
+			dirs[path] = true
+		} else {
+			files = append(files, path)
+		}
+	})
+	w.updateWatches(dirs)
+
+	notes := make(map[string]*watchedNote)
+	for _, file := range files {
+		fi, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		note := w.notes[file]
+		if note == nil || note.mtime != fi.ModTime().UnixNano() || note.size != fi.Size() {
+			note = &watchedNote{mtime: fi.ModTime().UnixNano(), size: fi.Size()}
+			if meta, err := ParseMeta(file); err == nil {
+				note.tags = append([]string{}, meta.Tags...)
+			}
+			note.links, _ = fileLinks(file, w.keys)
+		}
+		notes[file] = note
+	}
+	w.moveAccess(notes)
+	w.notes = notes
+	w.files = nil
+	for file := range notes {
+		w.files = append(w.files, file)
+	}
+	sort.Strings(w.files)
+	if w.idx.refresh(w.files) {
+		if err := w.idx.save(); err != nil {
+			log.Print(err)
+		}
+	}
+	w.updated = time.Now()
+}
+
+// updateWatches watches dirs and stops watching the directories that are
+// gone. Watches of renamed directories are removed before new ones are
+// added, since inotify hands out the same watch for the same directory.
+func (w *watcher) updateWatches(dirs map[string]bool) {
+	watched := make(map[string]bool)
+	for _, dir := range w.fsw.WatchList() {
+		watched[dir] = true
+		if !dirs[dir] {
+			w.fsw.Remove(dir)
+		}
+	}
+	for dir := range dirs {
+		if !watched[dir] {
+			if err := w.fsw.Add(dir); err != nil {
+				log.Print(err)
+			}
+		}
+	}
+}
+
+// moveAccess carries the access history of the notes renamed outside of
+// note over to their new names. A renamed note keeps its size and mtime,
+// which is how it is told apart from a note deleted and another created.
+func (w *watcher) moveAccess(notes map[string]*watchedNote) {
+	type key struct{ mtime, size int64 }
+	gone := make(map[key]string)
+	for file, note := range w.notes {
+		if notes[file] == nil && !ignored(filepath.Base(file), editorTempFiles) {
+			k := key{note.mtime, note.size}
+			if _, ok := gone[k]; ok {
+				gone[k] = ""
+			} else {
+				gone[k] = file
+			}
+		}
+	}
+	for file, note := range notes {
+		if w.notes[file] != nil || ignored(filepath.Base(file), editorTempFiles) {
+			continue
+		}
+		if old := gone[key{note.mtime, note.size}]; old != "" {
+			if err := moveAccess(w.root, old, file); err != nil {
+				log.Print(err)
+			}
+		}
+	}
+}
+
+func (w *watcher) serve(ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go w.handle(conn)
+	}
+}
+
+func (w *watcher) handle(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(queryTimeout))
+	var req daemonRequest
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		return
+	}
+	w.catchUp()
+	w.mu.Lock()
+	resp := w.answer(req)
+	w.mu.Unlock()
+	if err := json.NewEncoder(conn).Encode(resp); err != nil {
+		log.Print(err)
+	}
+}
+
+// answer answers req. w.mu must be held.
+func (w *watcher) answer(req daemonRequest) *daemonResponse {
+	switch req.Op {
+	case "files":
+		return &daemonResponse{Files: w.files}
+	case "tags":
+		tags := make(map[string][]string)
+		for file, note := range w.notes {
+			if note.tags != nil {
+				tags[file] = note.tags
+			}
+		}
+		return &daemonResponse{Tags: tags}
+	case "links":
+		links := make(map[string][]Link)
+		for file, note := range w.notes {
+			if len(note.links) > 0 {
+				links[file] = note.links
+			}
+		}
+		return &daemonResponse{Links: links}
+	case "candidates":
+		candidates := make(map[string]bool)
+		for _, file := range w.idx.candidates(w.files, req.Pattern) {
+			candidates[file] = true
+		}
+		var skip []string
+		for _, file := range w.files {
+			if !candidates[file] {
+				skip = append(skip, file)
+			}
+		}
+		return &daemonResponse{Skip: skip}
+	case "status":
+		return &daemonResponse{Status: &WatchStatus{
+			Pid:     os.Getpid(),
+			Root:    w.root,
+			Notes:   len(w.files),
+			Started: w.started,
+			Updated: w.updated,
+		}}
+	}
+	return &daemonResponse{Error: "Unknown query '" + req.Op + "'."}
+}
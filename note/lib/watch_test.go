@@ -0,0 +1,105 @@
+package lib
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func daemonFiles(dir string) []string {
+	resp, err := queryDaemon(dir, daemonRequest{Op: "files"})
+	if err != nil {
+		return nil
+	}
+	return resp.Files
+}
+
+func TestWatch(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "note")
+	defer os.RemoveAll(dir)
+	SetConfig(&Config{NotesDir: dir, Sort: SortName, UseDaemon: true})
+	defer SetConfig(nil)
+	a := path.Join(dir, "a.md")
+	b := path.Join(dir, "b.md")
+	ioutil.WriteFile(a, []byte("---\ntags: [go]\n---\nsee [[b]]\n"), 0644)
+	ioutil.WriteFile(b, []byte("nothing here\n"), 0644)
+
+	// No daemon is running yet.
+	status, err := Watching()
+	assert.Nil(t, err)
+	assert.Nil(t, status)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- Watch(ctx, 20*time.Millisecond)
+	}()
+	assert.Eventually(t, func() bool {
+		status, _ := Watching()
+		return status != nil
+	}, 5*time.Second, 10*time.Millisecond)
+	status, _ = Watching()
+	assert.Equal(t, 2, status.Notes)
+	assert.Equal(t, DaemonRunningError(dir), Watch(ctx, 0))
+
+	// Queries are answered by the daemon.
+	assert.Equal(t, []string{a, b}, daemonFiles(dir))
+	tags, err := Tags()
+	assert.Nil(t, err)
+	assert.Equal(t, []TagCount{{"go", 1}}, tags)
+	backlinks, err := Backlinks("b.md")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(backlinks))
+	assert.Equal(t, a, backlinks[0].File)
+	matches, err := Grep("see [[b", GrepOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{a}, MatchedFiles(matches))
+
+	// Changes are picked up, also in new folders.
+	os.Mkdir(path.Join(dir, "sub"), 0755)
+	note := path.Join(dir, "sub", "n.md")
+	ioutil.WriteFile(note, []byte("---\ntags: [go, rust]\n---\n"), 0644)
+	assert.Eventually(t, func() bool {
+		return len(daemonFiles(dir)) == 3
+	}, 5*time.Second, 10*time.Millisecond)
+	files, _ := ListWith("", Filter{Tags: []string{"rust"}})
+	assert.Equal(t, []string{note}, files)
+
+	// Renamed folders keep being watched and their notes keep their
+	// access history.
+	assert.Nil(t, recordAccess(note))
+	moved := path.Join(dir, "moved", "n.md")
+	assert.Nil(t, os.Rename(path.Join(dir, "sub"), path.Join(dir, "moved")))
+	assert.Eventually(t, func() bool {
+		files := daemonFiles(dir)
+		return len(files) == 3 && files[2] == moved
+	}, 5*time.Second, 10*time.Millisecond)
+	access := loadAccess(dir)
+	assert.Nil(t, access["sub/n.md"])
+	assert.NotNil(t, access["moved/n.md"])
+	added := path.Join(dir, "moved", "added.md")
+	ioutil.WriteFile(added, []byte("new\n"), 0644)
+	assert.Eventually(t, func() bool {
+		return len(daemonFiles(dir)) == 4
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{a, b, added, moved}, daemonFiles(dir))
+
+	// The socket is gone once the daemon stops.
+	cancel()
+	assert.Nil(t, <-done)
+	_, err = os.Stat(socketPath(dir))
+	assert.True(t, os.IsNotExist(err))
+	status, _ = Watching()
+	assert.Nil(t, status)
+}
+
+func TestDaemonCandidates(t *testing.T) {
+	// Notes the daemon doesn't know of yet are still searched.
+	kept := daemonCandidates([]string{"/n/a.md", "/n/b.md", "/n/new.md"}, []string{"/n/b.md"})
+	assert.Equal(t, []string{"/n/a.md", "/n/new.md"}, kept)
+}